@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func ConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and check the tobrew configuration",
+		Long: `Inspect and check the tobrew configuration file.
+
+Example:
+  tobrew config validate
+  tobrew config schema
+  tobrew config show --resolved
+  tobrew config migrate
+  tobrew config set github.user myname`,
+	}
+
+	cmd.AddCommand(configValidateCmd())
+	cmd.AddCommand(configSchemaCmd())
+	cmd.AddCommand(configShowCmd())
+	cmd.AddCommand(configMigrateCmd())
+	cmd.AddCommand(configSetCmd())
+
+	return cmd
+}
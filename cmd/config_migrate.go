@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/config"
+)
+
+func configMigrateCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate [file]",
+		Short: "Upgrade the config file to the current format",
+		Long: fmt.Sprintf(`Upgrade the config file to the current format (schema_version %d).
+
+tobrew reads older formats by upgrading them in memory; migrate rewrites
+the file once so it matches the documentation. Comments and key order are
+kept. The changes are shown as a diff.
+
+Extended configs are not changed, migrate them separately.
+
+Example:
+  tobrew config migrate
+  tobrew config migrate --dry-run`, config.SchemaVersion),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "tobrew.yaml"
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			before, after, changes, err := config.Migrate(path)
+			if err != nil {
+				return err
+			}
+			if string(before) == string(after) {
+				fmt.Printf("✓ %s is already at schema version %d\n", path, config.SchemaVersion)
+				return nil
+			}
+
+			printDiff(path, string(before), string(after))
+			fmt.Println()
+			for _, change := range changes {
+				fmt.Printf("  • %s\n", change)
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 Dry run, %s not changed\n", path)
+				return nil
+			}
+			if err := os.WriteFile(path, after, 0644); err != nil {
+				return fmt.Errorf("failed to write config file: %w", err)
+			}
+			fmt.Printf("✓ Migrated %s to schema version %d\n", path, config.SchemaVersion)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the changes without writing the file")
+
+	return cmd
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/config"
+)
+
+func configSchemaCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the config file",
+		Long: `Print the JSON Schema of tobrew.yaml, tobrew.json and tobrew.toml.
+
+Editors use it to complete keys and flag typos such as tap-repo instead
+of tap_repo. Files created by tobrew init already reference the
+published schema:
+
+  ` + config.SchemaURL + `
+
+Example:
+  tobrew config schema
+  tobrew config schema -o tobrew.schema.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := config.Schema()
+			if err != nil {
+				return fmt.Errorf("failed to generate schema: %w", err)
+			}
+			if output == "" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return fmt.Errorf("failed to write schema: %w", err)
+			}
+			fmt.Printf("✓ Created %s\n", output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the schema to a file instead of stdout")
+
+	return cmd
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/config"
+)
+
+func configSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <key> <value> [file]",
+		Short: "Set a value in the config file",
+		Long: `Set a value in the config file, keeping its comments and key order.
+
+Keys are dotted paths as shown by tobrew config show, with list items
+by index; an index one past the end adds an item. Values are text for
+string settings and YAML for everything else, so numbers, booleans and
+lists like [a, b] work as expected.
+
+YAML, JSON and TOML files can be edited. In TOML files, whole lists are
+set rather than single items.
+
+Example:
+  tobrew config set github.user myname
+  tobrew config set version.tag_format 'release-{{.Version}}'
+  tobrew config set github.private true
+  tobrew config set taps[1].repo homebrew-extra`,
+		Args: cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			key, text := args[0], args[1]
+			path := "tobrew.yaml"
+			if len(args) > 2 {
+				path = args[2]
+			}
+
+			value, err := config.ParseValue(key, text)
+			if err != nil {
+				return err
+			}
+			f, err := config.OpenFile(path)
+			if err != nil {
+				return err
+			}
+			if err := f.Set(key, value); err != nil {
+				return err
+			}
+			if err := f.Write(); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Set %s in %s\n", key, path)
+
+			// Point out problems with the new value, the file is written either way
+			problems, err := config.Validate(path)
+			if err != nil {
+				return nil
+			}
+			for _, p := range problems {
+				if p.Key == key || strings.HasPrefix(p.Key, key+".") || strings.HasPrefix(p.Key, key+"[") {
+					fmt.Printf("⚠️  %s\n", p)
+				}
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
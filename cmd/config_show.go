@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/config"
+)
+
+func configShowCmd() *cobra.Command {
+	var resolved bool
+
+	cmd := &cobra.Command{
+		Use:   "show [file]",
+		Short: "Print the config file",
+		Long: `Print the config file.
+
+With --resolved, print the effective config tobrew uses instead: merged
+onto the config it extends, with environment variables and includes
+expanded. Each value is annotated with the file and line it came from.
+
+Example:
+  tobrew config show
+  tobrew config show --resolved`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "tobrew.yaml"
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			var data []byte
+			var err error
+			if resolved {
+				data, err = config.Resolved(path)
+			} else {
+				data, err = os.ReadFile(path)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(string(data))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&resolved, "resolved", false, "Print the effective config and where each value came from")
+
+	return cmd
+}
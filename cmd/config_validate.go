@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/config"
+)
+
+func configValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [file]",
+		Short: "Check the config file and report every problem",
+		Long: `Check the config file and report every problem at once, with the
+line and column it was found at:
+
+  - Missing required fields and invalid setting values
+  - Placeholders left over from tobrew init, such as USERNAME
+  - Names that are not valid Homebrew formula names
+  - Homepages that are not http(s) URLs
+  - Licenses that are not SPDX identifiers (warning)
+  - Unknown keys, usually typos (warning)
+
+Exits with an error if any error was found; warnings alone pass.
+
+Example:
+  tobrew config validate
+  tobrew config validate release.yaml`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Problems are reported above, usage would only bury them
+			cmd.SilenceUsage = true
+			return runConfigValidate(args)
+		},
+	}
+
+	return cmd
+}
+
+func runConfigValidate(args []string) error {
+	path := "tobrew.yaml"
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	problems, err := config.Validate(path)
+	if err != nil {
+		return err
+	}
+
+	errors := 0
+	for _, p := range problems {
+		location := path
+		if p.File != "" {
+			location = p.File
+		}
+		if p.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", location, p.Line, p.Column)
+		}
+		fmt.Printf("%s: %s\n", location, p)
+		if !p.Warning {
+			errors++
+		}
+	}
+
+	if errors > 0 {
+		return fmt.Errorf("%s has %d error(s) and %d warning(s)", path, errors, len(problems)-errors)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("⚠️  %s is valid, with %d warning(s)\n", path, len(problems))
+		return nil
+	}
+	fmt.Printf("✅ %s is valid\n", path)
+	return nil
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/yejune/tobrew/internal/cargo"
+)
+
+// projectInfo is the metadata tobrew init detects from the project's files.
+// Empty fields were not found.
+type projectInfo struct {
+	Language    string
+	Name        string
+	Description string
+	License     string
+	Homepage    string
+	Version     string // current version, if the manifest has one
+	Host        string // git host of origin, e.g. github.com
+	User        string
+	Repo        string
+
+	Sources map[string]string // field name -> file it was detected from
+}
+
+// manifests lists the files that identify a project's language, in order of precedence
+var manifests = []struct {
+	file     string
+	language string
+	read     func(data []byte, info *projectInfo)
+}{
+	{"go.mod", "go", readGoMod},
+	{"Cargo.toml", "rust", readCargoToml},
+	{"package.json", "node", readPackageJSON},
+	{"pyproject.toml", "python", readPyproject},
+	{"composer.json", "php", readComposerJSON},
+}
+
+// detectProject collects project metadata from manifests, the LICENSE file
+// and the origin remote
+func detectProject() projectInfo {
+	info := projectInfo{Sources: map[string]string{}}
+
+	for _, m := range manifests {
+		data, err := os.ReadFile(m.file)
+		if err != nil {
+			continue
+		}
+		info.Language = m.language
+		info.Sources["language"] = m.file
+		m.read(data, &info)
+		for _, field := range []struct{ name, value string }{
+			{"name", info.Name},
+			{"description", info.Description},
+			{"license", info.License},
+			{"homepage", info.Homepage},
+			{"version", info.Version},
+		} {
+			if field.value != "" {
+				info.Sources[field.name] = m.file
+			}
+		}
+		break
+	}
+
+	if info.License == "" {
+		if license, file := detectLicenseFile(); license != "" {
+			info.License = license
+			info.Sources["license"] = file
+		}
+	}
+
+	if output, err := exec.Command("git", "remote", "get-url", "origin").Output(); err == nil {
+		remote := strings.TrimSpace(string(output))
+		if owner, repo, ok := strings.Cut(remotePath(remote), "/"); ok && !strings.Contains(repo, "/") {
+			info.Host = remoteHost(remote)
+			info.User = owner
+			info.Repo = repo
+			info.Sources["repository"] = "git remote origin"
+		}
+	}
+
+	// Fall back to the repository, then the directory, for the name
+	if info.Name == "" && info.Repo != "" {
+		info.Name = info.Repo
+		info.Sources["name"] = "git remote origin"
+	}
+	if info.Name == "" {
+		if dir, err := os.Getwd(); err == nil {
+			info.Name = filepath.Base(dir)
+		}
+	}
+	info.Name = strings.ToLower(info.Name)
+
+	return info
+}
+
+// remoteHost returns the host of an HTTPS, SSH or scp-style git URL
+func remoteHost(remote string) string {
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	// scp-style: git@host:owner/repo.git
+	host, _, _ := strings.Cut(remote, ":")
+	if _, h, ok := strings.Cut(host, "@"); ok {
+		host = h
+	}
+	return host
+}
+
+// majorSuffixRe matches the major version suffix of Go module paths
+var majorSuffixRe = regexp.MustCompile(`^v[0-9]+$`)
+
+func readGoMod(data []byte, info *projectInfo) {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		modulePath := strings.Trim(fields[1], `"`)
+		name := path.Base(modulePath)
+		if majorSuffixRe.MatchString(name) {
+			// example.com/tool/v2 is the tool module
+			name = path.Base(path.Dir(modulePath))
+		}
+		info.Name = name
+		return
+	}
+}
+
+func readCargoToml(data []byte, info *projectInfo) {
+	manifest, err := cargo.ParseManifest(data)
+	if err != nil {
+		return
+	}
+	info.Name = manifest.Name
+	info.Description = manifest.Description
+	info.License = manifest.License
+	info.Homepage = firstNonEmpty(manifest.Homepage, manifest.Repository)
+	info.Version = manifest.Version
+}
+
+func readPackageJSON(data []byte, info *projectInfo) {
+	var pkg struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description"`
+		License     interface{} `json:"license"`
+		Homepage    string      `json:"homepage"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return
+	}
+	// Scoped packages: @scope/name
+	info.Name = path.Base(pkg.Name)
+	info.Description = pkg.Description
+	info.License = licenseString(pkg.License)
+	info.Homepage = pkg.Homepage
+}
+
+func readPyproject(data []byte, info *projectInfo) {
+	var pyproject struct {
+		Project struct {
+			Name        string            `toml:"name"`
+			Description string            `toml:"description"`
+			License     interface{}       `toml:"license"`
+			URLs        map[string]string `toml:"urls"`
+		} `toml:"project"`
+		Tool struct {
+			Poetry struct {
+				Name        string `toml:"name"`
+				Description string `toml:"description"`
+				License     string `toml:"license"`
+				Homepage    string `toml:"homepage"`
+			} `toml:"poetry"`
+		} `toml:"tool"`
+	}
+	if toml.Unmarshal(data, &pyproject) != nil {
+		return
+	}
+	p, poetry := pyproject.Project, pyproject.Tool.Poetry
+	info.Name = firstNonEmpty(p.Name, poetry.Name)
+	info.Description = firstNonEmpty(p.Description, poetry.Description)
+	info.License = firstNonEmpty(licenseString(p.License), poetry.License)
+	info.Homepage = firstNonEmpty(p.URLs["Homepage"], p.URLs["homepage"], poetry.Homepage)
+}
+
+func readComposerJSON(data []byte, info *projectInfo) {
+	var composer struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description"`
+		License     interface{} `json:"license"`
+		Homepage    string      `json:"homepage"`
+	}
+	if json.Unmarshal(data, &composer) != nil {
+		return
+	}
+	// Composer names are vendor/package
+	info.Name = path.Base(composer.Name)
+	info.Description = composer.Description
+	info.License = licenseString(composer.License)
+	info.Homepage = composer.Homepage
+}
+
+// licenseString returns a manifest license field, which may be a string, a
+// list of alternatives or a {text = "..."} table
+func licenseString(v interface{}) string {
+	switch license := v.(type) {
+	case string:
+		return license
+	case []interface{}:
+		var ids []string
+		for _, id := range license {
+			if s, ok := id.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+		return strings.Join(ids, " OR ")
+	case map[string]interface{}:
+		if text, ok := license["text"].(string); ok && !strings.Contains(text, "\n") {
+			return text
+		}
+	}
+	return ""
+}
+
+// licenseMarkers identifies license files by a phrase of their text, most specific first
+var licenseMarkers = []struct {
+	phrase string
+	id     string
+}{
+	{"GNU AFFERO GENERAL PUBLIC LICENSE", "AGPL-3.0-only"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE Version 3", "LGPL-3.0-only"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE Version 2.1", "LGPL-2.1-only"},
+	{"GNU GENERAL PUBLIC LICENSE Version 3", "GPL-3.0-only"},
+	{"GNU GENERAL PUBLIC LICENSE Version 2", "GPL-2.0-only"},
+	{"Apache License", "Apache-2.0"},
+	{"Mozilla Public License Version 2.0", "MPL-2.0"},
+	{"This is free and unencumbered software released into the public domain", "Unlicense"},
+	{"ISC License", "ISC"},
+	{"Permission is hereby granted, free of charge", "MIT"},
+	{"Neither the name", "BSD-3-Clause"},
+	{"Redistribution and use in source and binary forms", "BSD-2-Clause"},
+}
+
+// detectLicenseFile identifies the license of the project's LICENSE file and
+// returns its SPDX identifier and the file name
+func detectLicenseFile() (string, string) {
+	for _, name := range []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "COPYING"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		// Compare with whitespace collapsed, license texts are often re-wrapped
+		text := strings.Join(strings.Fields(string(data)), " ")
+		for _, m := range licenseMarkers {
+			if strings.Contains(text, m.phrase) {
+				return m.id, name
+			}
+		}
+		return "", ""
+	}
+	return "", ""
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
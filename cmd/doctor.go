@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/auth"
+	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/forge"
+)
+
+// toolchains maps each language to the command its builds need
+var toolchains = map[string]string{
+	"go":     "go",
+	"rust":   "cargo",
+	"python": "python3",
+	"node":   "npm",
+	"php":    "composer",
+}
+
+// diagnosis collects the results of tobrew doctor's checks
+type diagnosis struct {
+	failures int
+	warnings int
+}
+
+func (d *diagnosis) ok(format string, a ...interface{}) {
+	fmt.Printf("  ✓ %s\n", fmt.Sprintf(format, a...))
+}
+
+func (d *diagnosis) warn(fix string, format string, a ...interface{}) {
+	d.warnings++
+	fmt.Printf("  ! %s\n", fmt.Sprintf(format, a...))
+	if fix != "" {
+		fmt.Printf("    Fix: %s\n", fix)
+	}
+}
+
+func (d *diagnosis) fail(fix string, format string, a ...interface{}) {
+	d.failures++
+	fmt.Printf("  ✗ %s\n", fmt.Sprintf(format, a...))
+	if fix != "" {
+		fmt.Printf("    Fix: %s\n", fix)
+	}
+}
+
+func DoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor [project]",
+		Short: "Check the environment for common release problems",
+		Long: `Check the environment for the problems that most often make
+releases fail, and print how to fix them:
+
+  - git is installed and user.name/user.email are set
+  - the origin remote points to github.user/github.repo
+  - every tap repository is reachable
+  - a forge token is present and has the scopes releases need
+  - the build toolchain for the configured language is installed,
+    with the targets of rust.targets
+
+Example:
+  tobrew doctor
+  tobrew doctor foo      # Project "foo" of a multi-project config`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Findings are reported above, usage would only bury them
+			cmd.SilenceUsage = true
+			return runDoctor(args)
+		},
+	}
+
+	return cmd
+}
+
+func runDoctor(args []string) error {
+	d := &diagnosis{}
+
+	fmt.Println("🩺 git")
+	gitOK := checkGit(d)
+
+	cfg, _, err := loadProjectConfig(args)
+	fmt.Println("\n🩺 Config")
+	if err != nil {
+		d.fail("run 'tobrew init' or fix tobrew.yaml", "%v", err)
+	} else {
+		d.ok("tobrew.yaml is valid")
+	}
+
+	if cfg != nil {
+		if gitOK {
+			fmt.Println("\n🩺 Repository")
+			checkOrigin(d, cfg)
+
+			fmt.Println("\n🩺 Taps")
+			for _, tap := range cfg.GetTaps() {
+				checkTapAccess(d, cfg, tap)
+			}
+		}
+
+		fmt.Println("\n🩺 Token")
+		checkToken(d, cfg)
+
+		fmt.Println("\n🩺 Toolchain")
+		checkToolchain(d, cfg)
+	}
+
+	fmt.Println()
+	switch {
+	case d.failures > 0:
+		return fmt.Errorf("%d problem(s) and %d warning(s) found", d.failures, d.warnings)
+	case d.warnings > 0:
+		fmt.Printf("⚠️  No problems, %d warning(s)\n", d.warnings)
+	default:
+		fmt.Println("✅ Everything looks good")
+	}
+	return nil
+}
+
+// checkGit checks that git is installed and has an identity to commit with
+func checkGit(d *diagnosis) bool {
+	output, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		d.fail("install git: https://git-scm.com/downloads", "git is not installed")
+		return false
+	}
+	d.ok("%s", strings.TrimSpace(string(output)))
+
+	for _, key := range []string{"user.name", "user.email"} {
+		value, _ := exec.Command("git", "config", key).Output()
+		if v := strings.TrimSpace(string(value)); v != "" {
+			d.ok("%s is %s", key, v)
+		} else {
+			d.fail(fmt.Sprintf("git config --global %s \"...\"", key), "%s is not set, tags and tap commits need it", key)
+		}
+	}
+	return true
+}
+
+// checkOrigin checks that the origin remote is the repository named in the config
+func checkOrigin(d *diagnosis, cfg *config.Config) {
+	want := cfg.GitHub.User + "/" + cfg.GitHub.Repo
+	fix := fmt.Sprintf("git remote add origin %s (or set-url if origin exists)", cfg.GetForge().CloneURL(cfg.GitHub.User, cfg.GitHub.Repo))
+
+	output, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		d.fail(fix, "no origin remote, tags can't be pushed")
+		return
+	}
+	remote := strings.TrimSpace(string(output))
+
+	if got := remotePath(remote); strings.EqualFold(got, want) {
+		d.ok("origin is %s", remote)
+	} else {
+		d.fail(fix+", or correct github.user/github.repo in tobrew.yaml",
+			"origin is %s, but tobrew.yaml releases %s", remote, want)
+	}
+}
+
+// remotePath returns the "owner/repo" part of an HTTPS, SSH or scp-style git URL
+func remotePath(remote string) string {
+	p := remote
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" {
+		p = u.Path
+	} else if _, rest, ok := strings.Cut(remote, ":"); ok {
+		// scp-style: git@host:owner/repo.git
+		p = rest
+	}
+	return strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+}
+
+// checkTapAccess checks that a tap repository exists and can be read with the
+// configured credentials. Push access can't be tested without pushing.
+func checkTapAccess(d *diagnosis, cfg *config.Config, tap config.TapConfig) {
+	tapURL := cfg.GetTapRepoURL(tap)
+
+	env := auth.GitEnv(cfg.GetForge(), cfg.GetToken(), tapURL)
+	if env == nil {
+		env = os.Environ()
+	}
+	// Fail instead of waiting for a password
+	env = append(env, "GIT_TERMINAL_PROMPT=0")
+
+	cmd := exec.Command("git", "ls-remote", "--heads", tapURL, tap.Branch)
+	cmd.Env = env
+	output, err := cmd.Output()
+	switch {
+	case err != nil:
+		d.fail(fmt.Sprintf("create %s or check your access to it", tapURL), "tap %s is not reachable", tap)
+	case strings.TrimSpace(string(output)) == "":
+		d.fail(fmt.Sprintf("push a %s branch to %s, or set the tap's branch in tobrew.yaml", tap.Branch, tap), "tap %s has no branch %s", tap, tap.Branch)
+	default:
+		d.ok("tap %s is reachable (%s)", tap, tapURL)
+	}
+}
+
+// checkToken checks that a token is available when releases need one, and
+// that the forge accepts it with enough scopes to push to the taps
+func checkToken(d *diagnosis, cfg *config.Config) {
+	f := cfg.GetForge()
+	envs := strings.Join(auth.TokenEnvs(cfg.GitHub.TokenEnv, f.Type()), " or ")
+
+	// Taps cloned over HTTPS from the forge are pushed with the token
+	needed := cfg.GitHub.Private
+	for _, tap := range cfg.GetTaps() {
+		if f.GitAuthHeader(cfg.GetTapRepoURL(tap), "token") != "" {
+			needed = true
+		}
+	}
+
+	token := cfg.GetToken()
+	if token == "" {
+		if needed {
+			d.warn("export "+envs+"=<token>", "no token in %s, git will fall back to its credential helper", envs)
+		} else {
+			d.ok("no token needed, no tap is pushed over HTTPS to %s", f.Type())
+		}
+		return
+	}
+	if f.TokenAPIURL() == "" {
+		d.ok("token present in %s, %s forges have no API to check it", envs, f.Type())
+		return
+	}
+
+	scopes, err := auth.Scopes(f, token)
+	if err != nil {
+		d.fail("create a new token and export it as "+envs, "token from %s: %v", envs, err)
+		return
+	}
+	if scopes == nil {
+		d.ok("token from %s is valid (scopes not reported, make sure it can push to the taps)", envs)
+		return
+	}
+
+	required := requiredScopes(f.Type(), cfg.GitHub.Private)
+	if len(required) == 0 || hasAnyScope(scopes, required) {
+		d.ok("token from %s is valid, scopes: %s", envs, strings.Join(scopes, ", "))
+		return
+	}
+	d.fail("grant the token one of these scopes: "+strings.Join(required, ", "),
+		"token from %s lacks the scopes to push, has: %s", envs, joinOrDash(scopes))
+}
+
+// requiredScopes returns the token scopes of which at least one is needed to push to taps
+func requiredScopes(forgeType string, private bool) []string {
+	switch forgeType {
+	case forge.TypeGitHub:
+		if private {
+			return []string{"repo"}
+		}
+		return []string{"repo", "public_repo"}
+	case forge.TypeGitLab:
+		return []string{"api", "write_repository"}
+	}
+	return nil
+}
+
+// hasAnyScope reports whether scopes contains one of wanted
+func hasAnyScope(scopes []string, wanted []string) bool {
+	for _, s := range scopes {
+		for _, w := range wanted {
+			if s == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkToolchain checks that the build toolchain of the configured language is installed
+func checkToolchain(d *diagnosis, cfg *config.Config) {
+	language := cfg.Language
+	if idx := strings.Index(language, "@"); idx > 0 {
+		language = language[:idx]
+	}
+
+	tool, ok := toolchains[language]
+	if !ok {
+		d.ok("language %s needs no toolchain check", cfg.Language)
+		return
+	}
+
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		d.fail(fmt.Sprintf("install %s and make sure it is on your PATH", tool), "%s is not installed, needed to build %s projects", tool, language)
+		return
+	}
+	d.ok("%s found at %s", tool, path)
+
+	if language == "rust" && len(cfg.Rust.Targets) > 0 {
+		checkRustTargets(d, cfg.Rust.Targets)
+	}
+}
+
+// checkRustTargets checks the standard library of each of rust.targets is
+// installed, which cross-compiling needs
+func checkRustTargets(d *diagnosis, targets []string) {
+	output, err := exec.Command("rustup", "target", "list", "--installed").Output()
+	if err != nil {
+		d.warn("install the targets with rustup, or make sure your toolchain has them", "rustup not found, can't check rust.targets are installed")
+		return
+	}
+	installed := strings.Fields(string(output))
+	for _, target := range targets {
+		if slices.Contains(installed, target) {
+			d.ok("rust target %s installed", target)
+		} else {
+			d.fail("rustup target add "+target, "rust target %s is not installed", target)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/version"
+)
+
+func HistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history [project]",
+		Short: "List past releases recorded in tobrew.lock",
+		Long: `List the release history recorded in tobrew.lock, newest first.
+
+Every release appends an entry with its tag, date, tagged commit,
+tarball SHA256, tap commits and who released it.
+
+Example:
+  tobrew history
+  tobrew history foo     # Project "foo" of a multi-project config`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runHistory,
+	}
+
+	return cmd
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	cfg, project, err := loadProjectConfig(args)
+	if err != nil {
+		return err
+	}
+
+	lockFile, err := version.LoadLock()
+	if err != nil {
+		return fmt.Errorf("failed to load lock file: %w", err)
+	}
+	lock := lockFile.Entry(project, cfg.GetTagFormat())
+
+	if len(lock.Releases) == 0 {
+		fmt.Println("No releases recorded in tobrew.lock yet")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tDATE\tCOMMIT\tSHA256\tTAPS\tRELEASED BY")
+	for i := len(lock.Releases) - 1; i >= 0; i-- {
+		r := lock.Releases[i]
+
+		taps := make([]string, 0, len(r.Taps))
+		for tap, sha := range r.Taps {
+			taps = append(taps, tap+"@"+shortSHA(sha))
+		}
+		sort.Strings(taps)
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Version,
+			r.Date.Format("2006-01-02 15:04"),
+			shortSHA(r.Commit),
+			shortSHA(r.SHA256),
+			joinOrDash(taps),
+			r.ReleasedBy,
+		)
+	}
+	return w.Flush()
+}
+
+// shortSHA abbreviates a commit or checksum for display
+func shortSHA(sha string) string {
+	if sha == "" {
+		return "-"
+	}
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// joinOrDash joins values with commas, or returns "-" if there are none
+func joinOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ", ")
+}
@@ -4,19 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/pelletier/go-toml/v2"
 	"github.com/spf13/cobra"
 	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/forge"
+	"github.com/yejune/tobrew/internal/github"
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	formatFlag   string
-	outputFlag   string
-	languageFlag string
+	formatFlag         string
+	outputFlag         string
+	languageFlag       string
+	interactiveFlag    bool
+	fromFormulaFlag    string
+	fromGoReleaserFlag string
 )
 
 func InitCmd() *cobra.Command {
@@ -32,14 +36,23 @@ Supports multiple formats:
 
 Example:
   tobrew init
+  tobrew init --interactive
+  tobrew init --from-formula Formula/mytool.rb
+  tobrew init --from-formula myorg/tap/mytool
+  tobrew init --from-goreleaser
   tobrew init --format json
   tobrew init --format toml -o release.toml`,
 		RunE: runInit,
 	}
 
+	cmd.Flags().BoolVarP(&interactiveFlag, "interactive", "i", false, "Ask for each setting, pre-filled from detection")
+	cmd.Flags().StringVar(&fromFormulaFlag, "from-formula", "", "Import an existing formula, a .rb file or owner/tap/name")
+	cmd.Flags().StringVar(&fromGoReleaserFlag, "from-goreleaser", "", "Import a GoReleaser config, found in the current directory if no path is given")
+	cmd.Flags().Lookup("from-goreleaser").NoOptDefVal = "auto"
+	cmd.MarkFlagsMutuallyExclusive("from-formula", "from-goreleaser")
 	cmd.Flags().StringVarP(&formatFlag, "format", "f", "yaml", "Config file format (yaml, json, toml)")
 	cmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Output file path (default: tobrew.{format})")
-	cmd.Flags().StringVarP(&languageFlag, "language", "l", "go", "Project language (go, rust, python, node, php, binary)\n"+
+	cmd.Flags().StringVarP(&languageFlag, "language", "l", "go", "Project language (go, rust, python, node, php, binary), detected if not set\n"+
 		"Supports version specification: php@8.4, python@3.11, node@20")
 
 	return cmd
@@ -51,21 +64,31 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unsupported format: %s (use yaml, json, or toml)", formatFlag)
 	}
 
-	// Validate language (allow version specification like php@8.4)
-	baseLanguage := languageFlag
-	if idx := strings.Index(languageFlag, "@"); idx > 0 {
-		baseLanguage = languageFlag[:idx]
+	// Learn what we can from manifests, LICENSE and the origin remote
+	info := detectProject()
+	var imported *importedFormula
+	var goreleaser *goreleaserImport
+	var err error
+	if fromFormulaFlag != "" {
+		if imported, err = importFormula(fromFormulaFlag, &info); err != nil {
+			return err
+		}
 	}
-
-	validLanguages := []string{"go", "rust", "python", "node", "php", "binary"}
-	isValid := false
-	for _, lang := range validLanguages {
-		if baseLanguage == lang {
-			isValid = true
-			break
+	if fromGoReleaserFlag != "" {
+		path := fromGoReleaserFlag
+		if path == "auto" {
+			path = ""
+		}
+		if goreleaser, err = importGoReleaser(path, &info); err != nil {
+			return err
 		}
 	}
-	if !isValid {
+	if !cmd.Flags().Changed("language") && info.Language != "" {
+		languageFlag = info.Language
+	}
+
+	// Validate language (allow version specification like php@8.4)
+	if !validLanguage(languageFlag) {
 		return fmt.Errorf("unsupported language: %s (use go, rust, python, node, php, or binary)\nVersion specification is supported: php@8.4, python@3.11, node@20", languageFlag)
 	}
 
@@ -80,23 +103,151 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("file already exists: %s (remove it first or use -o to specify different path)", outputFile)
 	}
 
-	// Try to detect project name from directory or go.mod
-	projectName := detectProjectName()
+	tapRepo := "homebrew-tap"
+	if imported != nil && imported.Tap != nil {
+		tapRepo = imported.Tap.Repo
+	}
+	if goreleaser != nil && goreleaser.Tap != nil {
+		tapRepo = goreleaser.Tap.Repo
+	}
+	createTap := false
+	if interactiveFlag {
+		createTap = runWizard(&info, &languageFlag, &tapRepo)
+	}
+
+	cfg := newConfig(info, languageFlag, tapRepo)
+	if imported != nil {
+		applyFormula(cfg, imported)
+	}
+	if goreleaser != nil {
+		applyGoReleaser(cfg, goreleaser)
+	}
+	if err := writeConfig(cfg, formatFlag, outputFile); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Created %s\n", outputFile)
+	if imported != nil {
+		if err := seedLock(cfg, imported); err != nil {
+			return err
+		}
+	}
+	if !interactiveFlag {
+		printDetected(info)
+	}
+	if imported != nil {
+		printSkipped(imported.Source, imported.Skipped)
+	}
+	if goreleaser != nil {
+		printSkipped(goreleaser.Source, goreleaser.Skipped)
+		if cfg.GitHub.User == config.PlaceholderUser {
+			fmt.Printf("\n⚠️  %s has no release.github and there is no git origin, set github.user in %s\n", goreleaser.Source, outputFile)
+		}
+	}
+
+	tapStep := fmt.Sprintf("Create GitHub repository named '%s'", tapRepo)
+	if (imported != nil && imported.Tap != nil) || (goreleaser != nil && goreleaser.Tap != nil) {
+		tapStep = ""
+	}
+	if createTap {
+		tap := cfg.GetTaps()[0]
+		created, err := github.EnsureTapRepo(cfg, tap)
+		switch {
+		case err != nil:
+			fmt.Printf("⚠️  %v\n", err)
+		case created:
+			fmt.Printf("✓ Created tap repository %s\n", tap)
+			tapStep = ""
+		default:
+			fmt.Printf("✓ Tap repository %s already exists\n", tap)
+			tapStep = ""
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Next steps:")
+	steps := []string{"Review the config file"}
+	if keys := placeholderKeys(cfg); len(keys) > 0 {
+		steps[0] = "Edit the config file and replace the placeholders in " + strings.Join(keys, ", ")
+	}
+	if tapStep != "" {
+		steps = append(steps, tapStep)
+	}
+	steps = append(steps, "Create a release: tobrew release")
+	for i, step := range steps {
+		fmt.Printf("  %d. %s\n", i+1, step)
+	}
+
+	return nil
+}
+
+// placeholderKeys returns the keys of cfg still holding a placeholder
+func placeholderKeys(cfg *config.Config) []string {
+	var keys []string
+	if strings.Contains(cfg.GitHub.User, config.PlaceholderUser) {
+		keys = append(keys, "github.user")
+	}
+	if strings.Contains(cfg.Homepage, config.PlaceholderUser) {
+		keys = append(keys, "homepage")
+	}
+	for i, tap := range cfg.Taps {
+		if strings.Contains(tap.Owner, config.PlaceholderUser) {
+			keys = append(keys, fmt.Sprintf("taps[%d].owner", i))
+		}
+	}
+	if cfg.Description == config.PlaceholderDescription {
+		keys = append(keys, "description")
+	}
+	return keys
+}
+
+// validLanguage reports whether language is supported, optionally with a
+// version specification like php@8.4
+func validLanguage(language string) bool {
+	if idx := strings.Index(language, "@"); idx > 0 {
+		language = language[:idx]
+	}
+	for _, lang := range []string{"go", "rust", "python", "node", "php", "binary"} {
+		if language == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// newConfig returns the initial config for a project. What wasn't detected
+// is filled with placeholders, which validation rejects.
+func newConfig(info projectInfo, language, tapRepo string) *config.Config {
+	projectName := info.Name
 
 	// Get language-specific template
-	buildCmd, installScript, testScript := getLanguageTemplate(projectName, languageFlag)
+	buildCmd, installScript, testScript := getLanguageTemplate(projectName, language)
+
+	user := firstNonEmpty(info.User, config.PlaceholderUser)
+	repo := firstNonEmpty(info.Repo, projectName)
+	var forgeConfig config.ForgeConfig
+	webURL := fmt.Sprintf("https://github.com/%s/%s", user, repo)
+	switch {
+	case info.Host == "gitlab.com":
+		forgeConfig.Type = forge.TypeGitLab
+		webURL = fmt.Sprintf("https://gitlab.com/%s/%s", user, repo)
+	case info.Host != "" && info.Host != "github.com":
+		// Self-hosted forges need their type set by hand
+		fmt.Printf("⚠️  origin is on %s, set forge.type and forge.url for it\n", info.Host)
+	}
 
-	// Create default config
 	cfg := &config.Config{
-		Name:        projectName,
-		Language:    languageFlag,
-		Description: "Description of your project",
-		Homepage:    fmt.Sprintf("https://github.com/USERNAME/%s", projectName),
-		License:     "MIT",
+		SchemaVersion: config.SchemaVersion,
+		Name:          projectName,
+		Language:      language,
+		Description:   firstNonEmpty(info.Description, config.PlaceholderDescription),
+		Homepage:      firstNonEmpty(info.Homepage, webURL),
+		License:       firstNonEmpty(info.License, "MIT"),
+		Forge:         forgeConfig,
 		GitHub: config.GitHubConfig{
-			User:    "USERNAME",
-			Repo:    projectName,
-			TapRepo: "homebrew-tap",
+			User:    user,
+			Repo:    repo,
+			TapRepo: tapRepo,
 		},
 		Build: config.BuildConfig{
 			Command: buildCmd,
@@ -110,61 +261,42 @@ Run '%s --help' to get started.`, projectName, projectName),
 		},
 	}
 
-	// Write config in requested format
+	// Cargo.toml already holds the version, releases keep it up to date
+	if language == "rust" && info.Version != "" {
+		cfg.Version.Source = config.VersionSourceCargo
+	}
+
+	return cfg
+}
+
+// writeConfig writes cfg to path in the given format (yaml, json or toml)
+func writeConfig(cfg *config.Config, format, path string) error {
 	var data []byte
 	var err error
 
-	switch formatFlag {
+	// Point editors at the JSON Schema, so keys complete and typos are flagged
+	switch format {
 	case "yaml":
 		data, err = yaml.Marshal(cfg)
+		data = append([]byte("# yaml-language-server: $schema="+config.SchemaURL+"\n"), data...)
 	case "json":
+		cfg.Schema = config.SchemaURL
 		data, err = json.MarshalIndent(cfg, "", "  ")
 	case "toml":
 		data, err = toml.Marshal(cfg)
+		data = append([]byte("#:schema "+config.SchemaURL+"\n\n"), data...)
 	}
 
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
-
-	fmt.Printf("✓ Created %s\n", outputFile)
-	fmt.Println()
-	fmt.Println("Next steps:")
-	fmt.Println("  1. Edit the config file and update USERNAME, description, etc.")
-	fmt.Println("  2. Create GitHub repository named 'homebrew-tap'")
-	fmt.Printf("  3. Create a release: tobrew release\n")
-
 	return nil
 }
 
-func detectProjectName() string {
-	// Try current directory name
-	dir, err := os.Getwd()
-	if err == nil {
-		return filepath.Base(dir)
-	}
-
-	// Try go.mod
-	if data, err := os.ReadFile("go.mod"); err == nil {
-		// Simple parsing - just get first line "module github.com/user/project"
-		lines := string(data)
-		var modulePath string
-		fmt.Sscanf(lines, "module %s", &modulePath)
-		if modulePath != "" {
-			parts := filepath.SplitList(modulePath)
-			if len(parts) > 0 {
-				return filepath.Base(parts[len(parts)-1])
-			}
-		}
-	}
-
-	return "myapp"
-}
-
 // getLanguageTemplate returns language-specific build command and formula scripts
 func getLanguageTemplate(projectName, language string) (buildCmd, installScript, testScript string) {
 	// Check for versioned languages (e.g., python@3.11, php@8.4)
@@ -215,3 +347,37 @@ bin.install "%s"`, projectName),
 			fmt.Sprintf(`assert_match "%s", shell_output("#{bin}/%s --version")`, projectName, projectName)
 	}
 }
+
+// printSkipped lists what an import left out
+func printSkipped(source string, skipped []string) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("Not imported from %s, tobrew has no equivalent for:\n", source)
+	for _, s := range skipped {
+		fmt.Printf("  %s\n", s)
+	}
+}
+
+// printDetected lists the settings init took from the project's files
+func printDetected(info projectInfo) {
+	if len(info.Sources) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Detected:")
+	for _, field := range []struct{ name, value string }{
+		{"language", info.Language},
+		{"name", info.Name},
+		{"description", info.Description},
+		{"license", info.License},
+		{"homepage", info.Homepage},
+		{"version", info.Version},
+		{"repository", info.User + "/" + info.Repo},
+	} {
+		if source, ok := info.Sources[field.name]; ok {
+			fmt.Printf("  %-12s %s (from %s)\n", field.name, field.value, source)
+		}
+	}
+}
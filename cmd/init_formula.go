@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/formula"
+	"github.com/yejune/tobrew/internal/github"
+	"github.com/yejune/tobrew/internal/version"
+)
+
+// formulaDirs are the directories taps keep formulas in, in the order brew searches them
+var formulaDirs = []string{"Formula", "HomebrewFormula", ""}
+
+// importedFormula is an existing formula tobrew init converts to a config
+type importedFormula struct {
+	*formula.Parsed
+	Source string
+	Tap    *config.TapConfig // tap the formula was read from, nil for files
+}
+
+// importFormula reads the formula at ref, a .rb file or owner/tap/name, and
+// fills info with its metadata
+func importFormula(ref string, info *projectInfo) (*importedFormula, error) {
+	content, name, tap, err := readFormulaRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := formula.ParseFormula(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ref, err)
+	}
+
+	source := filepath.Base(ref)
+	set := func(field string, dst *string, value string) {
+		if value != "" {
+			*dst = value
+			info.Sources[field] = source
+		}
+	}
+	set("name", &info.Name, name)
+	set("description", &info.Description, parsed.Description)
+	set("license", &info.License, parsed.License)
+	set("homepage", &info.Homepage, parsed.Homepage)
+
+	// The source URL names the repository releases are made from
+	if host, owner, repo, ok := repoFromURL(parsed.URL); ok {
+		info.Host, info.User, info.Repo = host, owner, repo
+		info.Sources["repository"] = source
+	}
+	if language := formulaLanguage(parsed.Dependencies); language != "" {
+		info.Language = language
+		info.Sources["language"] = source
+	}
+
+	return &importedFormula{Parsed: parsed, Source: source, Tap: tap}, nil
+}
+
+// readFormulaRef returns the formula at ref, its name and, for
+// owner/tap/name references, the tap it was found in
+func readFormulaRef(ref string) (string, string, *config.TapConfig, error) {
+	if _, err := os.Stat(ref); err == nil || strings.HasSuffix(ref, ".rb") {
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("failed to read formula: %w", err)
+		}
+		return string(data), strings.TrimSuffix(filepath.Base(ref), ".rb"), nil, nil
+	}
+
+	parts := strings.Split(ref, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", nil, fmt.Errorf("%s is neither a formula file nor owner/tap/name", ref)
+	}
+	owner, name := parts[0], parts[2]
+	tap := config.TapConfig{Owner: owner, Repo: "homebrew-" + strings.TrimPrefix(parts[1], "homebrew-")}
+
+	// A tap brew already has is read in place
+	if output, err := exec.Command("brew", "--repository", tap.TapName()).Output(); err == nil {
+		dir := strings.TrimSpace(string(output))
+		for _, formulaDir := range formulaDirs {
+			tap.Directory = formulaDir
+			if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(tap.FormulaPath(name)))); err == nil {
+				return string(data), name, &tap, nil
+			}
+		}
+	}
+
+	fmt.Printf("📥 Fetching %s from %s...\n", name, tap)
+	cfg := &config.Config{Name: name, GitHub: config.GitHubConfig{User: owner, TapRepo: tap.Repo}}
+	tap = cfg.GetTaps()[0]
+	content, formulaDir, err := github.FindFormula(cfg, tap, formulaDirs)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if content == "" {
+		return "", "", nil, fmt.Errorf("no formula %s in %s", name, tap)
+	}
+	tap.Directory = formulaDir
+	return content, name, &tap, nil
+}
+
+// repoFromURL returns the forge host and repository of a GitHub or GitLab
+// source URL, e.g. https://github.com/owner/repo/archive/v1.0.0.tar.gz
+func repoFromURL(rawURL string) (host, owner, repo string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Host != "github.com" && u.Host != "gitlab.com") {
+		return "", "", "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 3 {
+		return "", "", "", false
+	}
+	return u.Host, parts[0], parts[1], true
+}
+
+// formulaLanguage returns the language whose dependency the formula has,
+// or "" if none matches
+func formulaLanguage(deps []config.Dependency) string {
+	for _, dep := range deps {
+		if !validLanguage(dep.Name) {
+			continue
+		}
+		if own, ok := formula.LanguageDependency(dep.Name); ok && own == dep {
+			return dep.Name
+		}
+	}
+	return ""
+}
+
+// applyFormula replaces the generated formula settings of cfg with the
+// imported ones. Dependencies the language brings are left out.
+func applyFormula(cfg *config.Config, f *importedFormula) {
+	cfg.Formula.Install = firstNonEmpty(f.Install, cfg.Formula.Install)
+	cfg.Formula.Test = f.Test
+	cfg.Formula.Caveats = f.Caveats
+
+	own, hasOwn := formula.LanguageDependency(cfg.Language)
+	for _, dep := range f.Dependencies {
+		if hasOwn && dep == own {
+			continue
+		}
+		cfg.Formula.Dependencies = append(cfg.Formula.Dependencies, dep)
+	}
+
+	if f.Tap != nil {
+		useTap(cfg, *f.Tap)
+	}
+}
+
+// useTap points cfg at the tap an imported formula is published to. Taps
+// that differ from the github.tap_repo defaults get a taps entry.
+func useTap(cfg *config.Config, tap config.TapConfig) {
+	entry := config.TapConfig{Repo: cfg.GitHub.TapRepo, Directory: tap.Directory}
+	if tap.Owner != cfg.GitHub.User {
+		entry.Owner = tap.Owner
+	}
+	if tap.Branch != "main" {
+		entry.Branch = tap.Branch
+	}
+	if entry == (config.TapConfig{Repo: entry.Repo}) {
+		return
+	}
+	cfg.GitHub.TapRepo = ""
+	cfg.Taps = []config.TapConfig{entry}
+}
+
+// seedLock records the imported formula's version and checksum as the last
+// release, unless the lock file already has one
+func seedLock(cfg *config.Config, f *importedFormula) error {
+	if f.Version == "" {
+		fmt.Printf("⚠️  No version found in %s, tobrew.lock not written\n", f.Source)
+		return nil
+	}
+
+	lock, err := version.LoadLock()
+	if err != nil {
+		return err
+	}
+	if lock.Version != "" {
+		fmt.Printf("⚠️  tobrew.lock already records %s, left unchanged\n", lock.Version)
+		return nil
+	}
+
+	format := cfg.GetTagFormat()
+	entry := lock.Entry("", format)
+	entry.Version = format.Format(strings.TrimPrefix(f.Version, "v"))
+	entry.SHA256 = f.SHA256
+	if err := lock.Save(); err != nil {
+		return fmt.Errorf("failed to write tobrew.lock: %w", err)
+	}
+	fmt.Printf("✓ Created tobrew.lock at %s\n", entry.Version)
+	return nil
+}
@@ -0,0 +1,432 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/formula"
+	"gopkg.in/yaml.v3"
+)
+
+// goreleaserFiles are the names GoReleaser looks for its config under
+var goreleaserFiles = []string{".goreleaser.yaml", ".goreleaser.yml", "goreleaser.yaml", "goreleaser.yml"}
+
+// goreleaserConfig is the part of a GoReleaser config tobrew can translate
+type goreleaserConfig struct {
+	ProjectName string `yaml:"project_name"`
+	Before      struct {
+		Hooks []string `yaml:"hooks"`
+	} `yaml:"before"`
+	Builds  []goreleaserBuild `yaml:"builds"`
+	Brews   []goreleaserBrew  `yaml:"brews"`
+	Release struct {
+		GitHub goreleaserRepo `yaml:"github"`
+		GitLab goreleaserRepo `yaml:"gitlab"`
+	} `yaml:"release"`
+}
+
+type goreleaserBuild struct {
+	Builder string     `yaml:"builder"`
+	Main    string     `yaml:"main"`
+	Binary  string     `yaml:"binary"`
+	Env     []string   `yaml:"env"`
+	Flags   stringList `yaml:"flags"`
+	Ldflags stringList `yaml:"ldflags"`
+}
+
+type goreleaserBrew struct {
+	Name         string         `yaml:"name"`
+	Repository   goreleaserRepo `yaml:"repository"`
+	Tap          goreleaserRepo `yaml:"tap"` // before GoReleaser v1.19
+	Directory    string         `yaml:"directory"`
+	Folder       string         `yaml:"folder"` // before GoReleaser v1.19
+	Description  string         `yaml:"description"`
+	Homepage     string         `yaml:"homepage"`
+	License      string         `yaml:"license"`
+	Install      string         `yaml:"install"`
+	ExtraInstall string         `yaml:"extra_install"`
+	Test         string         `yaml:"test"`
+	Caveats      string         `yaml:"caveats"`
+	Dependencies []struct {
+		Name string `yaml:"name"`
+		Type string `yaml:"type"`
+		OS   string `yaml:"os"`
+	} `yaml:"dependencies"`
+}
+
+type goreleaserRepo struct {
+	Owner  string `yaml:"owner"`
+	Name   string `yaml:"name"`
+	Branch string `yaml:"branch"`
+	Token  string `yaml:"token"`
+}
+
+// stringList is a YAML list of strings that may also be written as a single string
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// goreleaserHandled lists the keys of each GoReleaser section init translates
+var goreleaserHandled = map[string][]string{
+	"":                   {"version", "project_name", "before", "builds", "brews", "release"},
+	"before":             {"hooks"},
+	"builds":             {"id", "builder", "main", "binary", "env", "flags", "ldflags"},
+	"brews":              {"name", "repository", "tap", "directory", "folder", "description", "homepage", "license", "install", "extra_install", "test", "caveats", "dependencies"},
+	"brews.repository":   {"owner", "name", "branch", "token"},
+	"brews.tap":          {"owner", "name", "branch", "token"},
+	"brews.dependencies": {"name", "type", "os"},
+	"release":            {"github", "gitlab"},
+	"release.github":     {"owner", "name"},
+	"release.gitlab":     {"owner", "name"},
+}
+
+// goreleaserImport is a GoReleaser config translated for tobrew init
+type goreleaserImport struct {
+	Source       string
+	Language     string
+	BuildCommand string
+	Install      string
+	Test         string
+	Caveats      string
+	Dependencies []config.Dependency
+	TokenEnv     string
+	Tap          *config.TapConfig
+
+	// Skipped lists the settings that have no tobrew equivalent
+	Skipped []string
+}
+
+var (
+	goreleaserTemplateRe = regexp.MustCompile(`\{\{-?\s*([^}]*?)\s*-?\}\}`)
+	goreleaserEnvRe      = regexp.MustCompile(`^\{\{\s*\.Env\.(\w+)\s*\}\}$`)
+)
+
+// importGoReleaser reads a GoReleaser config and fills info with its
+// metadata. path "" finds the config in the current directory.
+func importGoReleaser(path string, info *projectInfo) (*goreleaserImport, error) {
+	if path == "" {
+		for _, name := range goreleaserFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("no GoReleaser config found, pass its path to --from-goreleaser")
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GoReleaser config: %w", err)
+	}
+
+	var gr goreleaserConfig
+	if err := yaml.Unmarshal(data, &gr); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	g := &goreleaserImport{Source: path, Language: "go"}
+	g.skipUnhandled("", "", raw)
+
+	// Metadata, with the brew's settings winning over the project's
+	name := gr.ProjectName
+	var brew goreleaserBrew
+	if len(gr.Brews) > 0 {
+		brew = gr.Brews[0]
+		name = firstNonEmpty(brew.Name, name)
+		for i := 1; i < len(gr.Brews); i++ {
+			g.Skipped = append(g.Skipped, fmt.Sprintf("brews[%d] (one formula per config, or use projects)", i))
+		}
+	}
+	set := func(field string, dst *string, value string) {
+		if value = g.text("brews[0]."+field, value, name); value != "" {
+			*dst = value
+			info.Sources[field] = path
+		}
+	}
+	set("name", &info.Name, name)
+	set("description", &info.Description, brew.Description)
+	set("license", &info.License, brew.License)
+	set("homepage", &info.Homepage, brew.Homepage)
+
+	for _, release := range []struct {
+		repo goreleaserRepo
+		host string
+	}{{gr.Release.GitHub, "github.com"}, {gr.Release.GitLab, "gitlab.com"}} {
+		if release.repo.Owner != "" && release.repo.Name != "" {
+			info.Host, info.User, info.Repo = release.host, release.repo.Owner, release.repo.Name
+			info.Sources["repository"] = path
+		}
+	}
+
+	g.translateBuilds(gr, info.Name)
+	if len(gr.Builds) > 0 {
+		info.Language = g.Language
+		info.Sources["language"] = path
+	}
+	g.translateBrew(brew, info.Name)
+
+	return g, nil
+}
+
+// translateBuilds turns the GoReleaser builds into the build command and a
+// formula install that builds every binary from source
+func (g *goreleaserImport) translateBuilds(gr goreleaserConfig, name string) {
+	var commands, installs []string
+	commands = append(commands, gr.Before.Hooks...)
+	seenEnv := map[string]bool{}
+
+	for i, b := range gr.Builds {
+		key := fmt.Sprintf("builds[%d]", i)
+		if b.Builder != "" && b.Builder != "go" {
+			if b.Builder == "rust" && len(gr.Builds) == 1 {
+				g.Language = "rust"
+				continue
+			}
+			g.Skipped = append(g.Skipped, fmt.Sprintf("%s (%s builder)", key, b.Builder))
+			continue
+		}
+
+		binary := g.text(key+".binary", b.Binary, name)
+		binary = firstNonEmpty(binary, name)
+		main := firstNonEmpty(b.Main, ".")
+
+		var env []string
+		for _, e := range b.Env {
+			if e = g.text(key+".env", e, name); e != "" {
+				env = append(env, e)
+			}
+		}
+		ldflags := g.ldflags(key, b.Ldflags, name)
+
+		// The build command runs before tagging, with no version to embed
+		command := "go build"
+		if len(b.Flags) > 0 {
+			command += " " + strings.Join(b.Flags, " ")
+		}
+		command += " -o build/" + binary + " " + main
+		if len(env) > 0 {
+			command = strings.Join(env, " ") + " " + command
+		}
+		commands = append(commands, command)
+
+		// The formula builds with Homebrew's standard arguments, ldflags
+		// referring to the formula's version
+		args := []string{}
+		if ldflags != "" {
+			args = append(args, "ldflags: "+strconv.Quote(ldflags))
+		}
+		if binary != name || len(gr.Builds) > 1 {
+			args = append(args, fmt.Sprintf(`output: bin/"%s"`, binary))
+		}
+		stdArgs := "*std_go_args"
+		if len(args) > 0 {
+			stdArgs = "*std_go_args(" + strings.Join(args, ", ") + ")"
+		}
+		install := `system "go", "build", ` + stdArgs
+		for _, flag := range b.Flags {
+			install += ", " + strconv.Quote(flag)
+		}
+		if main != "." {
+			install += ", " + strconv.Quote(main)
+		}
+		for _, e := range env {
+			if k, v, ok := strings.Cut(e, "="); ok && !seenEnv[e] {
+				seenEnv[e] = true
+				installs = append(installs, fmt.Sprintf("ENV[%q] = %q", k, v))
+			}
+		}
+		installs = append(installs, install)
+	}
+
+	if len(commands) > 0 && g.Language == "go" {
+		g.BuildCommand = strings.Join(commands, " && ")
+	}
+	g.Install = strings.Join(installs, "\n")
+}
+
+// ldflags joins a build's ldflags, with the version template replaced by
+// the formula's version. Flags using other templates, such as the commit,
+// are dropped.
+func (g *goreleaserImport) ldflags(key string, flags []string, name string) string {
+	var result []string
+	for _, line := range flags {
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			if field == "-X" && i+1 < len(fields) {
+				i++
+				field = "-X " + fields[i]
+			}
+			translated, ok := translateTemplate(field, name, "#{version}")
+			if !ok {
+				g.Skipped = append(g.Skipped, fmt.Sprintf("%s.ldflags: %s", key, field))
+				continue
+			}
+			result = append(result, translated)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// translateBrew takes the tap, scripts and dependencies of a brews entry
+func (g *goreleaserImport) translateBrew(brew goreleaserBrew, name string) {
+	repo := brew.Repository
+	if repo.Name == "" {
+		repo = brew.Tap
+	}
+	if repo.Name != "" {
+		g.Tap = &config.TapConfig{
+			Owner:     repo.Owner,
+			Repo:      repo.Name,
+			Branch:    repo.Branch,
+			Directory: firstNonEmpty(brew.Directory, brew.Folder),
+		}
+		if m := goreleaserEnvRe.FindStringSubmatch(repo.Token); m != nil {
+			g.TokenEnv = m[1]
+		} else if repo.Token != "" {
+			g.Skipped = append(g.Skipped, "brews[0].repository.token")
+		}
+	}
+
+	// GoReleaser installs prebuilt binaries, the formula builds them instead
+	var install []string
+	for _, script := range []struct{ key, value string }{
+		{"brews[0].install", brew.Install},
+		{"brews[0].extra_install", brew.ExtraInstall},
+	} {
+		for _, line := range strings.Split(strings.TrimSpace(script.value), "\n") {
+			trimmed := strings.TrimSpace(line)
+			switch translated, ok := translateTemplate(line, name, "#{version}"); {
+			case trimmed == "":
+			case strings.HasPrefix(trimmed, "bin.install "):
+				g.Skipped = append(g.Skipped, fmt.Sprintf("%s: %s (the formula builds the binaries instead)", script.key, trimmed))
+			case !ok:
+				g.Skipped = append(g.Skipped, fmt.Sprintf("%s: %s", script.key, trimmed))
+			default:
+				install = append(install, translated)
+			}
+		}
+	}
+	if len(install) > 0 {
+		g.Install = strings.TrimSpace(g.Install + "\n" + strings.Join(install, "\n"))
+	}
+
+	g.Test = g.text("brews[0].test", strings.TrimSpace(brew.Test), name)
+	g.Caveats = g.text("brews[0].caveats", strings.TrimSpace(brew.Caveats), name)
+
+	for i, dep := range brew.Dependencies {
+		if dep.OS != "" {
+			g.Skipped = append(g.Skipped, fmt.Sprintf("brews[0].dependencies[%d] (%s only)", i, dep.OS))
+			continue
+		}
+		g.Dependencies = append(g.Dependencies, config.Dependency{Name: dep.Name, Type: dep.Type})
+	}
+}
+
+// text translates the templates of a setting, skipping it if any are left
+func (g *goreleaserImport) text(key, value, name string) string {
+	translated, ok := translateTemplate(value, name, "#{version}")
+	if !ok {
+		g.Skipped = append(g.Skipped, key)
+		return ""
+	}
+	return translated
+}
+
+// translateTemplate replaces the GoReleaser templates tobrew has values for:
+// the project name and the version. It reports false if others remain.
+func translateTemplate(value, name, version string) (string, bool) {
+	ok := true
+	result := goreleaserTemplateRe.ReplaceAllStringFunc(value, func(tmpl string) string {
+		switch goreleaserTemplateRe.FindStringSubmatch(tmpl)[1] {
+		case ".ProjectName":
+			return name
+		case ".Version":
+			return version
+		}
+		ok = false
+		return tmpl
+	})
+	return result, ok
+}
+
+// skipUnhandled adds the keys of section init has no translation for to
+// the skipped settings, descending into the sections it translates. key is
+// where value is, with list indexes, e.g. builds[0].
+func (g *goreleaserImport) skipUnhandled(section, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		handled, known := goreleaserHandled[section]
+		if !known {
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !slices.Contains(handled, k) {
+				g.Skipped = append(g.Skipped, joinKey(key, k))
+				continue
+			}
+			g.skipUnhandled(joinKey(section, k), joinKey(key, k), v[k])
+		}
+	case []interface{}:
+		for i, item := range v {
+			g.skipUnhandled(section, fmt.Sprintf("%s[%d]", key, i), item)
+		}
+	}
+}
+
+// joinKey appends name to a dotted key path
+func joinKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
+
+// applyGoReleaser replaces the generated build and formula settings of cfg
+// with the translated ones
+func applyGoReleaser(cfg *config.Config, g *goreleaserImport) {
+	cfg.Build.Command = firstNonEmpty(g.BuildCommand, cfg.Build.Command)
+	cfg.Formula.Install = firstNonEmpty(g.Install, cfg.Formula.Install)
+	cfg.Formula.Test = firstNonEmpty(g.Test, cfg.Formula.Test)
+	cfg.Formula.Caveats = g.Caveats
+	cfg.GitHub.TokenEnv = g.TokenEnv
+
+	own, hasOwn := formula.LanguageDependency(cfg.Language)
+	for _, dep := range g.Dependencies {
+		if hasOwn && dep == own {
+			continue
+		}
+		cfg.Formula.Dependencies = append(cfg.Formula.Dependencies, dep)
+	}
+
+	if g.Tap != nil {
+		useTap(cfg, *g.Tap)
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/config"
+)
+
+// wizard asks questions on stdin, offering a default for each
+type wizard struct {
+	in  *bufio.Reader
+	eof bool // input ended, every further question takes its default
+}
+
+// ask prints question and returns the answer, or def if the answer is empty.
+// At the end of input every question takes its default.
+func (w *wizard) ask(question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	line, err := w.in.ReadString('\n')
+	if err == io.EOF {
+		w.eof = true
+		fmt.Println()
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// confirm asks a yes/no question
+func (w *wizard) confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer := strings.ToLower(w.ask(question+" ("+hint+")", ""))
+	if answer == "" {
+		return def
+	}
+	return answer == "y" || answer == "yes"
+}
+
+// runWizard asks for the settings of a new config, pre-filled from info, and
+// reports whether the tap repository should be created
+func runWizard(info *projectInfo, language *string, tapRepo *string) bool {
+	w := &wizard{in: bufio.NewReader(os.Stdin)}
+
+	fmt.Println("📝 Creating a tobrew config, press Enter to keep the suggested value")
+	fmt.Println()
+
+	for {
+		name := w.ask("Formula name", info.Name)
+		if config.ValidFormulaName(name) {
+			info.Name = name
+			break
+		}
+		fmt.Println("   Use lowercase letters, digits and - _ . + @")
+		if w.eof {
+			break
+		}
+	}
+
+	info.Description = w.ask("Description", info.Description)
+
+	license := firstNonEmpty(info.License, "MIT")
+	fmt.Printf("   Common licenses: %s\n", strings.Join(config.CommonLicenses, ", "))
+	for {
+		answer := w.ask("License (SPDX)", license)
+		if config.ValidLicense(answer) {
+			license = answer
+			break
+		}
+		if suggestions := config.SuggestLicenses(answer); len(suggestions) > 0 {
+			fmt.Printf("   Not an SPDX identifier, did you mean: %s\n", strings.Join(suggestions, ", "))
+		} else {
+			fmt.Println("   Not an SPDX identifier, see https://spdx.org/licenses/")
+		}
+		if w.confirm(fmt.Sprintf("   Use %q anyway?", answer), false) {
+			license = answer
+			break
+		}
+		if w.eof {
+			break
+		}
+	}
+	info.License = license
+
+	for {
+		info.User = w.ask("GitHub user or organization", info.User)
+		if info.User != "" || w.eof {
+			break
+		}
+	}
+	info.Repo = w.ask("Repository", firstNonEmpty(info.Repo, info.Name))
+	*tapRepo = w.ask("Tap repository", *tapRepo)
+
+	for {
+		lang := w.ask("Language (go, rust, python, node, php, binary)", *language)
+		if validLanguage(lang) {
+			*language = lang
+			break
+		}
+		fmt.Println("   Unsupported language")
+		if w.eof {
+			break
+		}
+	}
+
+	fmt.Println()
+	return w.confirm(fmt.Sprintf("Create %s/%s on GitHub if it doesn't exist?", info.User, *tapRepo), false)
+}
@@ -2,16 +2,17 @@ package cmd
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
-	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/auth"
 	"github.com/yejune/tobrew/internal/config"
 	"github.com/yejune/tobrew/internal/formula"
 	"github.com/yejune/tobrew/internal/github"
@@ -22,11 +23,13 @@ var (
 	majorFlag bool
 	minorFlag bool
 	patchFlag bool
+
+	freshCloneFlag bool
 )
 
 func ReleaseCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "release",
+		Use:   "release [project]",
 		Short: "Create a complete release and update Homebrew tap",
 		Long: `Create a complete release with automatic version bumping:
 
@@ -36,40 +39,46 @@ Examples:
   tobrew release              # Patch: v1.0.0 → v1.0.1
   tobrew release --minor      # Minor: v1.0.1 → v1.1.0
   tobrew release --major      # Major: v1.1.0 → v2.0.0
+  tobrew release foo          # Release project "foo" of a multi-project config
 
 The version is automatically managed in tobrew.lock file.
 
 Process:
-  1. Load current version from tobrew.lock
+  1. Load current version from tobrew.lock (or git tags, or Cargo.toml)
   2. Bump version according to flags
   3. Build the project
   4. Create and push git tag
   5. Download release tarball and calculate SHA256
+     (rust.targets: upload binary archives to the release)
   6. Generate Homebrew formula
-  7. Update homebrew-tap repository
+  7. Update every configured homebrew tap repository
   8. Save new version to tobrew.lock`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: runRelease,
 	}
 
 	cmd.Flags().BoolVar(&majorFlag, "major", false, "Increment major version (v1.0.0 → v2.0.0)")
 	cmd.Flags().BoolVar(&minorFlag, "minor", false, "Increment minor version (v1.0.0 → v1.1.0)")
 	cmd.Flags().BoolVar(&patchFlag, "patch", false, "Increment patch version (v1.0.0 → v1.0.1) - default")
+	cmd.Flags().BoolVar(&freshCloneFlag, "fresh-clone", false, "Clone the tap repositories again instead of reusing the cached checkout")
 
 	return cmd
 }
 
 func runRelease(cmd *cobra.Command, args []string) error {
-	// Load config
-	cfg, err := config.Load("")
+	// Load config of the project to release
+	cfg, project, err := loadProjectConfig(args)
 	if err != nil {
 		return err
 	}
 
 	// Load lock file
-	lock, err := version.LoadLock()
+	lockFile, err := version.LoadLock()
 	if err != nil {
 		return fmt.Errorf("failed to load version: %w", err)
 	}
+	tagFormat := cfg.GetTagFormat()
+	lock := lockFile.Entry(project, tagFormat)
 
 	// Determine bump type
 	bumpType := version.BumpPatch // default
@@ -94,43 +103,35 @@ func runRelease(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("cannot use multiple version bump flags together")
 	}
 
-	// Check if we need to sync with remote
-	currentVersion := lock.Version
-	needsRemoteSync := !lock.IsSameMachine()
-
-	if needsRemoteSync {
-		fmt.Printf("🔄 Different machine detected, syncing with remote...\n")
-	}
-
-	// Bump version first to check for tag conflict
-	newVersion, err := lock.Bump(bumpType)
-	if err != nil {
-		return fmt.Errorf("failed to bump version: %w", err)
-	}
-
-	// Check for tag conflict
-	if tagExists(newVersion) {
-		fmt.Printf("⚠️  Tag %s already exists, syncing with remote...\n", newVersion)
-		needsRemoteSync = true
-	}
-
-	// Sync with remote if needed
-	if needsRemoteSync {
-		latestTag, err := getLatestRemoteTag()
+	// Determine current and new version
+	var currentTag, newTag string
+	if cfg.VersionFromGit() {
+		// The highest matching tag is the current version, the lock is only a record
+		fmt.Println("🔄 Reading remote tags...")
+		currentTag, err = getLatestRemoteTag(tagFormat)
 		if err != nil {
 			return fmt.Errorf("failed to get latest remote tag: %w", err)
 		}
-		if compareVersions(latestTag, currentVersion) > 0 {
-			fmt.Printf("   Lock file (%s) → remote (%s)\n", currentVersion, latestTag)
-			currentVersion = latestTag
-			lock.Version = latestTag
-			// Re-bump from latest
-			newVersion, err = lock.Bump(bumpType)
-			if err != nil {
-				return fmt.Errorf("failed to bump version: %w", err)
-			}
+		lock.Version = currentTag
+		newTag, err = lock.Bump(bumpType, tagFormat)
+		if err != nil {
+			return fmt.Errorf("failed to bump version: %w", err)
+		}
+		if tagExists(newTag) {
+			return fmt.Errorf("tag %s already exists locally but not on origin, push or delete it first", newTag)
+		}
+	} else if cfg.VersionFromCargo() {
+		currentTag, newTag, err = bumpFromCargo(cfg, lock, bumpType, tagFormat)
+		if err != nil {
+			return err
+		}
+	} else {
+		currentTag, newTag, err = bumpFromLock(cfg, lock, bumpType, tagFormat)
+		if err != nil {
+			return err
 		}
 	}
+	newVersion := tagFormat.Version(newTag)
 
 	// Check for uncommitted changes
 	if hasUncommittedChanges() {
@@ -138,8 +139,8 @@ func runRelease(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("🚀 Starting release process for %s\n", cfg.Name)
-	fmt.Printf("   Current version: %s\n", currentVersion)
-	fmt.Printf("   New version:     %s\n\n", newVersion)
+	fmt.Printf("   Current version: %s\n", currentTag)
+	fmt.Printf("   New version:     %s\n\n", newTag)
 
 	// Confirm
 	fmt.Print("Continue? (Y/n): ")
@@ -149,16 +150,51 @@ func runRelease(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("release cancelled")
 	}
 
+	// The new version goes into Cargo.toml before the build, so the binaries report it
+	// Until it is committed, a failure puts their previous contents back
+	var versionFiles []string
+	restoreVersion := func() {}
+	if cfg.VersionFromCargo() {
+		fmt.Printf("\n📝 Setting version %s in %s...\n", newVersion, cfg.CargoManifest())
+		versionFiles, restoreVersion, err = setCargoVersion(cfg.CargoManifest(), newVersion)
+		if err != nil {
+			return fmt.Errorf("failed to set version: %w", err)
+		}
+		fmt.Printf("✓ Updated %s\n", strings.Join(versionFiles, ", "))
+	}
+
 	// Step 1: Build
 	fmt.Println("\n📦 Building project...")
 	if err := buildProject(cfg); err != nil {
+		restoreVersion()
 		return fmt.Errorf("build failed: %w", err)
 	}
+	var archiveFiles []string
+	if len(cfg.Rust.Targets) > 0 {
+		fmt.Println("\n📦 Building binaries for rust.targets...")
+		archiveFiles, err = buildTargets(cfg, newVersion)
+		if err != nil {
+			restoreVersion()
+			return fmt.Errorf("build failed: %w", err)
+		}
+	}
 	fmt.Println("✓ Build successful")
 
+	if len(versionFiles) > 0 {
+		fmt.Println("\n📌 Committing the version bump...")
+		if err := commitVersion(versionFiles, newTag); err != nil {
+			restoreVersion()
+			return fmt.Errorf("failed to commit version bump: %w", err)
+		}
+		if err := pushVersion(); err != nil {
+			return fmt.Errorf("failed to push the version bump, which is committed locally: push it and run the release again, or drop it with 'git reset --hard HEAD~1': %w", err)
+		}
+		fmt.Println("✓ Version bump committed and pushed")
+	}
+
 	// Step 2: Git tag
-	fmt.Printf("\n🏷️  Creating git tag %s...\n", newVersion)
-	if err := createGitTag(newVersion); err != nil {
+	fmt.Printf("\n🏷️  Creating git tag %s...\n", newTag)
+	if err := createGitTag(newTag); err != nil {
 		return fmt.Errorf("git tag failed: %w", err)
 	}
 	fmt.Println("✓ Git tag created and pushed")
@@ -169,8 +205,8 @@ func runRelease(cmd *cobra.Command, args []string) error {
 
 	// Step 3: Download and calculate SHA256
 	fmt.Println("\n🔐 Calculating SHA256 checksum...")
-	tarballURL := cfg.GetTarballURL(newVersion)
-	sha256sum, err := downloadAndHash(tarballURL)
+	tarballURL := cfg.GetTarballURL(newTag)
+	sha256sum, err := downloadAndHash(cfg, tarballURL)
 	if err != nil {
 		return fmt.Errorf("failed to download/hash tarball: %w", err)
 	}
@@ -179,9 +215,20 @@ func runRelease(cmd *cobra.Command, args []string) error {
 	// Update lock file with SHA256
 	lock.UpdateSHA256(sha256sum)
 
+	var archives []formula.Archive
+	var assets map[string]string
+	if len(archiveFiles) > 0 {
+		fmt.Println("\n📤 Uploading binary archives...")
+		archives, assets, err = publishArchives(cfg, newTag, archiveFiles)
+		if err != nil {
+			return fmt.Errorf("failed to publish archives: %w", err)
+		}
+	}
+
 	// Step 4: Generate formula
 	fmt.Println("\n📝 Generating Homebrew formula...")
-	formulaContent, err := formula.Generate(cfg, newVersion, sha256sum)
+	taps := cfg.GetTaps()
+	formulaContent, err := formula.Generate(cfg, newTag, sha256sum, archives, taps[0].Directory)
 	if err != nil {
 		return fmt.Errorf("formula generation failed: %w", err)
 	}
@@ -193,36 +240,145 @@ func runRelease(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("✓ Formula generated: %s\n", formulaFile)
 
-	// Step 5: Update homebrew-tap
-	fmt.Println("\n🍺 Updating homebrew-tap repository...")
-	if err := github.UpdateTap(cfg, formulaContent, newVersion); err != nil {
-		return fmt.Errorf("tap update failed: %w", err)
-	}
-	fmt.Println("✓ Homebrew tap updated")
+	// Step 5: Update homebrew taps
+	// Every tap is attempted even if an earlier one fails, so a broken
+	// tap doesn't cost the updates already pushed to the others.
+	tapOpts := github.Options{FreshClone: freshCloneFlag}
+	tapErrs := make([]error, len(taps))
+	tapCommits := map[string]string{}
+	failed := 0
+	for i, tap := range taps {
+		fmt.Printf("\n🍺 Updating tap %s...\n", tap)
+		// The formula can differ between taps (require paths depend on the directory)
+		var tapCommit string
+		tapFormula, err := formula.Generate(cfg, newTag, sha256sum, archives, tap.Directory)
+		if err == nil {
+			tapCommit, err = github.UpdateTap(cfg, tap, tapFormula, newVersion, tapOpts)
+		}
+		if err != nil {
+			tapErrs[i] = err
+			failed++
+			fmt.Printf("✗ Tap %s failed: %v\n", tap, err)
+			continue
+		}
+		fmt.Printf("✓ Tap %s updated\n", tap)
+		tapCommits[tap.String()] = tapCommit
+	}
+	printTapSummary(taps, tapErrs)
+
+	// Record the release in the history
+	lock.AddRelease(version.Release{
+		Version:    newTag,
+		Date:       lock.LastRelease,
+		Commit:     tagCommit(newTag),
+		URL:        tarballURL,
+		SHA256:     sha256sum,
+		Assets:     assets,
+		Taps:       tapCommits,
+		ReleasedBy: gitUser(),
+	})
 
 	// Step 6: Save lock file
-	fmt.Println("\n💾 Saving version lock file...")
-	lock.UpdateFingerprint()
-	if err := lock.Save(); err != nil {
-		return fmt.Errorf("failed to save lock file: %w", err)
+	// The tag is already pushed, so the lock is saved even if some taps failed
+	if cfg.WritesLock() {
+		fmt.Println("\n💾 Saving version lock file...")
+		if err := lockFile.Save(); err != nil {
+			return fmt.Errorf("failed to save lock file: %w", err)
+		}
+		fmt.Println("✓ Version saved to tobrew.lock")
+	}
+
+	if failed == len(taps) {
+		return fmt.Errorf("tap update failed for all %d tap(s)", len(taps))
 	}
-	fmt.Println("✓ Version saved to tobrew.lock")
 
 	// Success!
-	fmt.Println("\n✅ Release complete!")
+	if failed > 0 {
+		fmt.Println("\n⚠️  Release complete with tap failures")
+	} else {
+		fmt.Println("\n✅ Release complete!")
+	}
 	fmt.Println()
-	fmt.Printf("Version:  %s\n", newVersion)
+	fmt.Printf("Version:  %s\n", newTag)
 	fmt.Printf("Released: %s\n", lock.LastRelease.Format(time.RFC3339))
 	fmt.Println()
 	fmt.Printf("Users can now install with:\n")
-	fmt.Printf("  brew install %s/tap/%s\n", cfg.GitHub.User, cfg.Name)
+	for i, tap := range taps {
+		if tapErrs[i] != nil {
+			continue
+		}
+		if cfg.NeedsTapURL(tap) {
+			fmt.Printf("  brew tap %s %s\n", tap.TapName(), cfg.GetTapRepoURL(tap))
+		}
+		fmt.Printf("  brew install %s/%s\n", tap.TapName(), cfg.Name)
+	}
 	fmt.Println()
 	fmt.Printf("Or upgrade with:\n")
 	fmt.Printf("  brew upgrade %s\n", cfg.Name)
+	if cfg.GitHub.Private {
+		fmt.Println()
+		fmt.Printf("This is a private repository: users need %s set to a token with read access.\n",
+			formula.StrategyTokenEnv(cfg.GetForge().Type()))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("tap update failed for %d of %d tap(s)", failed, len(taps))
+	}
 
 	return nil
 }
 
+// loadProjectConfig loads the config and resolves the project named by the
+// optional first argument, as used by commands taking a [project] argument
+func loadProjectConfig(args []string) (*config.Config, string, error) {
+	cfg, err := config.Load("")
+	if err != nil {
+		return nil, "", err
+	}
+
+	project := ""
+	if len(args) > 0 {
+		project = args[0]
+	}
+	cfg, err = cfg.Project(project)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, project, nil
+}
+
+// bumpFromLock bumps the version stored in the lock. The lock is first
+// compared with the tags on origin: if it is behind, the release either
+// continues from the latest remote tag or is refused, per version.behind_remote.
+func bumpFromLock(cfg *config.Config, lock *version.Lock, bumpType version.BumpType, format *version.TagFormat) (currentTag string, newTag string, err error) {
+	currentTag = lock.Version
+
+	latestTag, err := getLatestRemoteTag(format)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get latest remote tag: %w", err)
+	}
+	if version.Compare(format.Version(latestTag), format.Version(currentTag)) > 0 {
+		if cfg.Version.BehindRemote == config.BehindRemoteFail {
+			return "", "", fmt.Errorf("tobrew.lock (%s) is behind the latest tag on origin (%s), run 'tobrew sync' first", currentTag, latestTag)
+		}
+		fmt.Printf("🔄 Lock file (%s) is behind remote (%s), continuing from remote\n", currentTag, latestTag)
+		currentTag = latestTag
+		lock.Version = latestTag
+	}
+
+	newTag, err = lock.Bump(bumpType, format)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to bump version: %w", err)
+	}
+
+	// A local tag that never reached origin, e.g. from an interrupted release
+	if tagExists(newTag) {
+		return "", "", fmt.Errorf("tag %s already exists locally but not on origin, push or delete it first", newTag)
+	}
+
+	return currentTag, newTag, nil
+}
+
 func buildProject(cfg *config.Config) error {
 	if cfg.Build.Command == "" {
 		return fmt.Errorf("build.command not specified in config")
@@ -238,6 +394,21 @@ func buildProject(cfg *config.Config) error {
 	return cmd.Run()
 }
 
+// printTapSummary prints the per-tap result of a release
+func printTapSummary(taps []config.TapConfig, errs []error) {
+	fmt.Println("\n📋 Tap summary:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  TAP\tBRANCH\tSTATUS")
+	for i, tap := range taps {
+		status := "✓ updated"
+		if errs[i] != nil {
+			status = "✗ " + errs[i].Error()
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", tap, tap.Branch, status)
+	}
+	w.Flush()
+}
+
 func tagExists(version string) bool {
 	cmd := exec.Command("git", "tag", "-l", version)
 	output, err := cmd.Output()
@@ -247,63 +418,78 @@ func tagExists(version string) bool {
 	return strings.TrimSpace(string(output)) == version
 }
 
-func getLatestRemoteTag() (string, error) {
-	// Fetch remote tags
-	fetchCmd := exec.Command("git", "fetch", "--tags")
-	fetchCmd.Run() // ignore error, might not have remote
-
-	// Get all tags sorted by version
-	cmd := exec.Command("git", "tag", "-l", "v*", "--sort=-v:refname")
-	output, err := cmd.Output()
+// getLatestRemoteTag returns the highest release tag matching format on
+// origin, read with git ls-remote so no fetch is needed. An unreachable
+// origin is an error: local tags may be stale.
+func getLatestRemoteTag(format *version.TagFormat) (string, error) {
+	output, err := lsRemote("--tags", "--refs", "origin")
 	if err != nil {
 		return "", err
 	}
 
-	tags := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(tags) == 0 || tags[0] == "" {
-		return "v0.0.0", nil
+	// Lines look like "<sha>\trefs/tags/<tag>"
+	var tags []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if _, ref, ok := strings.Cut(line, "\t"); ok {
+			tags = append(tags, strings.TrimPrefix(ref, "refs/tags/"))
+		}
 	}
-
-	return tags[0], nil
+	return latestTag(tags, format), nil
 }
 
-// compareVersions compares two semver strings (v1.2.3 format)
-// Returns: 1 if a > b, -1 if a < b, 0 if equal
-func compareVersions(a, b string) int {
-	parseVersion := func(v string) (int, int, int) {
-		v = strings.TrimPrefix(v, "v")
-		parts := strings.Split(v, ".")
-		if len(parts) != 3 {
-			return 0, 0, 0
+// lsRemote runs git ls-remote with args, which name the remote, and
+// returns its output
+func lsRemote(args ...string) (string, error) {
+	output, err := exec.Command("git", append([]string{"ls-remote"}, args...)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git ls-remote origin: %s", strings.TrimSpace(string(exitErr.Stderr)))
 		}
-		major, _ := strconv.Atoi(parts[0])
-		minor, _ := strconv.Atoi(parts[1])
-		patch, _ := strconv.Atoi(parts[2])
-		return major, minor, patch
+		return "", fmt.Errorf("git ls-remote origin: %w", err)
 	}
+	return string(output), nil
+}
 
-	aMajor, aMinor, aPatch := parseVersion(a)
-	bMajor, bMinor, bPatch := parseVersion(b)
+// getLatestLocalTag returns the highest release tag matching format in the local repository
+func getLatestLocalTag(format *version.TagFormat) (string, error) {
+	output, err := exec.Command("git", "tag", "-l", format.Pattern()).Output()
+	if err != nil {
+		return "", err
+	}
+	return latestTag(strings.Split(strings.TrimSpace(string(output)), "\n"), format), nil
+}
 
-	if aMajor != bMajor {
-		if aMajor > bMajor {
-			return 1
+// latestTag returns the tag with the highest version among tags matching
+// format, or the 0.0.0 tag if none match
+func latestTag(tags []string, format *version.TagFormat) string {
+	latest := format.Format("0.0.0")
+	for _, tag := range tags {
+		if v, ok := format.Parse(tag); ok && version.Compare(v, format.Version(latest)) > 0 {
+			latest = tag
 		}
-		return -1
 	}
-	if aMinor != bMinor {
-		if aMinor > bMinor {
-			return 1
-		}
-		return -1
+	return latest
+}
+
+// tagCommit returns the commit a tag points to, or "" if unknown
+func tagCommit(tag string) string {
+	output, err := exec.Command("git", "rev-list", "-n", "1", tag).Output()
+	if err != nil {
+		return ""
 	}
-	if aPatch != bPatch {
-		if aPatch > bPatch {
-			return 1
-		}
-		return -1
+	return strings.TrimSpace(string(output))
+}
+
+// gitUser returns the configured git identity as "Name <email>"
+func gitUser() string {
+	name, _ := exec.Command("git", "config", "user.name").Output()
+	email, _ := exec.Command("git", "config", "user.email").Output()
+	user := strings.TrimSpace(string(name))
+	if e := strings.TrimSpace(string(email)); e != "" {
+		user = strings.TrimSpace(user + " <" + e + ">")
 	}
-	return 0
+	return user
 }
 
 func createGitTag(version string) error {
@@ -328,8 +514,8 @@ func createGitTag(version string) error {
 	return pushCmd.Run()
 }
 
-func downloadAndHash(url string) (string, error) {
-	resp, err := http.Get(url)
+func downloadAndHash(cfg *config.Config, url string) (string, error) {
+	resp, err := auth.Get(cfg.GetForge(), cfg.GetToken(), url)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/yejune/tobrew/internal/cargo"
+	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/formula"
+	"github.com/yejune/tobrew/internal/github"
+	"github.com/yejune/tobrew/internal/version"
+)
+
+// bumpFromCargo bumps the version in Cargo.toml. Tags on origin newer than
+// it mean Cargo.toml was not kept up to date, which is refused rather than
+// guessed at.
+func bumpFromCargo(cfg *config.Config, lock *version.Lock, bumpType version.BumpType, format *version.TagFormat) (currentTag string, newTag string, err error) {
+	manifest, err := cargo.ReadManifest(cfg.CargoManifest())
+	if err != nil {
+		return "", "", err
+	}
+	if manifest.VersionFromWorkspace {
+		return "", "", fmt.Errorf("%s inherits its version from the workspace, which version.source: cargo can't bump", cfg.CargoManifest())
+	}
+	if manifest.Version == "" {
+		return "", "", fmt.Errorf("%s has no version", cfg.CargoManifest())
+	}
+	currentTag = format.Format(manifest.Version)
+
+	latestTag, err := getLatestRemoteTag(format)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get latest remote tag: %w", err)
+	}
+	if version.Compare(format.Version(latestTag), manifest.Version) > 0 {
+		return "", "", fmt.Errorf("%s (%s) is behind the latest tag on origin (%s), update its version first", cfg.CargoManifest(), manifest.Version, latestTag)
+	}
+
+	lock.Version = currentTag
+	newTag, err = lock.Bump(bumpType, format)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to bump version: %w", err)
+	}
+	if tagExists(newTag) {
+		return "", "", fmt.Errorf("tag %s already exists locally but not on origin, push or delete it first", newTag)
+	}
+	return currentTag, newTag, nil
+}
+
+// setCargoVersion writes version to Cargo.toml and Cargo.lock. The returned
+// function puts back their previous contents, so a release failing before
+// the version bump is committed leaves the working tree clean.
+func setCargoVersion(manifest, version string) ([]string, func(), error) {
+	paths := cargo.VersionFiles(manifest)
+	originals := make([][]byte, len(paths))
+	for i, file := range paths {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		originals[i] = data
+	}
+	restore := func() {
+		for i, file := range paths {
+			if err := os.WriteFile(file, originals[i], 0644); err != nil {
+				fmt.Printf("⚠️  Failed to restore %s: %v\n", file, err)
+				continue
+			}
+			// Unstage it too, the commit may have failed after git add
+			exec.Command("git", "reset", "-q", "--", file).Run()
+			fmt.Printf("↩️  Restored %s\n", file)
+		}
+	}
+
+	files, err := cargo.SetVersion(manifest, version)
+	if err != nil {
+		restore()
+		return nil, nil, err
+	}
+	return files, restore, nil
+}
+
+// commitVersion commits the files holding the new version. Files git
+// doesn't track, like an ignored Cargo.lock, are left out.
+func commitVersion(files []string, tag string) error {
+	add := exec.Command("git", append([]string{"add", "-u", "--"}, files...)...)
+	add.Stderr = os.Stderr
+	if err := add.Run(); err != nil {
+		return err
+	}
+
+	commit := exec.Command("git", "commit", "-m", "Release "+tag)
+	commit.Stdout = os.Stdout
+	commit.Stderr = os.Stderr
+	return commit.Run()
+}
+
+// pushVersion pushes the version bump commit, so the release tag points at it
+func pushVersion() error {
+	push := exec.Command("git", "push", "origin", "HEAD")
+	push.Stdout = os.Stdout
+	push.Stderr = os.Stderr
+	return push.Run()
+}
+
+// buildTargets builds the binary for each of rust.targets and packs it into
+// a <name>-<version>-<target>.tar.gz archive. It returns the archive paths.
+func buildTargets(cfg *config.Config, newVersion string) ([]string, error) {
+	targetDir, err := cargoTargetDir(cfg.CargoManifest())
+	if err != nil {
+		return nil, err
+	}
+	outDir := filepath.Join(targetDir, "tobrew")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var archives []string
+	for _, target := range cfg.Rust.Targets {
+		fmt.Printf("   %s\n", target)
+		build := exec.Command("cargo", "build", "--release", "--manifest-path", cfg.CargoManifest(), "--target", target, "--bin", cfg.RustBinary())
+		build.Stdout = os.Stdout
+		build.Stderr = os.Stderr
+		if err := build.Run(); err != nil {
+			return nil, fmt.Errorf("cargo build for %s: %w (is the target installed? rustup target add %s)", target, err, target)
+		}
+
+		binary := filepath.Join(targetDir, target, "release", cfg.RustBinary())
+		archive := filepath.Join(outDir, fmt.Sprintf("%s-%s-%s.tar.gz", cfg.Name, newVersion, target))
+		if err := packBinary(binary, archive); err != nil {
+			return nil, fmt.Errorf("failed to pack %s: %w", target, err)
+		}
+		archives = append(archives, archive)
+	}
+	return archives, nil
+}
+
+// cargoTargetDir returns the directory cargo builds into, which workspaces
+// and CARGO_TARGET_DIR move away from the crate
+func cargoTargetDir(manifest string) (string, error) {
+	output, err := exec.Command("cargo", "metadata", "--format-version", "1", "--no-deps", "--manifest-path", manifest).Output()
+	if err != nil {
+		return "", fmt.Errorf("cargo metadata: %w", err)
+	}
+	var metadata struct {
+		TargetDirectory string `json:"target_directory"`
+	}
+	if err := json.Unmarshal(output, &metadata); err != nil {
+		return "", fmt.Errorf("failed to parse cargo metadata: %w", err)
+	}
+	return metadata.TargetDirectory, nil
+}
+
+// packBinary writes a tar.gz archive holding the binary at its top level,
+// where bin.install finds it
+func packBinary(binary, archive string) error {
+	src, err := os.Open(binary)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	header := &tar.Header{
+		Name:    filepath.Base(binary),
+		Mode:    0755,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, src); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// publishArchives uploads the archives to the release of tag and returns
+// them for the formula, along with their checksums by file name for the
+// release history
+func publishArchives(cfg *config.Config, tag string, files []string) ([]formula.Archive, map[string]string, error) {
+	urls, err := github.UploadAssets(cfg, tag, files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var archives []formula.Archive
+	assets := map[string]string{}
+	for i, file := range files {
+		sum, err := hashFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := filepath.Base(file)
+		archives = append(archives, formula.Archive{
+			Target: cfg.Rust.Targets[i],
+			URL:    urls[name],
+			SHA256: sum,
+		})
+		assets[name] = sum
+		fmt.Printf("✓ %s\n", name)
+	}
+	return archives, assets, nil
+}
+
+// hashFile returns the SHA256 of a file
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
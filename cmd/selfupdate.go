@@ -11,6 +11,8 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/auth"
+	"github.com/yejune/tobrew/internal/forge"
 )
 
 const (
@@ -117,8 +119,15 @@ func runSelfUpdate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// githubGet performs a GET against GitHub, authorized with GITHUB_TOKEN/GH_TOKEN
+// when set to avoid the anonymous rate limit
+func githubGet(url string) (*http.Response, error) {
+	gh, _ := forge.New(forge.TypeGitHub, "", "")
+	return auth.Get(gh, auth.Token("", forge.TypeGitHub), url)
+}
+
 func getLatestVersion() (string, error) {
-	resp, err := http.Get(githubAPI)
+	resp, err := githubGet(githubAPI)
 	if err != nil {
 		return "", err
 	}
@@ -152,7 +161,7 @@ func getLatestVersion() (string, error) {
 }
 
 func downloadFile(filepath string, url string) error {
-	resp, err := http.Get(url)
+	resp, err := githubGet(url)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/cargo"
+	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/formula"
+	"github.com/yejune/tobrew/internal/github"
+	"github.com/yejune/tobrew/internal/version"
+)
+
+// releaseStatus is the state of a project as reported by tobrew status
+type releaseStatus struct {
+	Config        string      `json:"config"`
+	ConfigValid   bool        `json:"config_valid"`
+	ConfigError   string      `json:"config_error,omitempty"`
+	Project       string      `json:"project,omitempty"`
+	VersionSource string      `json:"version_source,omitempty"`
+	LockVersion   string      `json:"lock_version,omitempty"`
+	CargoVersion  string      `json:"cargo_version,omitempty"`
+	LocalTag      string      `json:"local_tag,omitempty"`
+	RemoteTag     string      `json:"remote_tag,omitempty"`
+	CommitsSince  *int        `json:"commits_since_release"` // null when no release tag is present locally
+	Clean         bool        `json:"clean"`
+	Taps          []tapStatus `json:"taps,omitempty"`
+	Problems      []string    `json:"problems"`
+	Ready         bool        `json:"ready"`
+}
+
+// tapStatus is the state of the formula published in one tap
+type tapStatus struct {
+	Tap            string `json:"tap"`
+	FormulaVersion string `json:"formula_version,omitempty"`
+	UpToDate       bool   `json:"up_to_date"`
+	SHA256         string `json:"sha256,omitempty"`
+	SHA256Matches  bool   `json:"sha256_matches"`
+	Error          string `json:"error,omitempty"`
+}
+
+func StatusCmd() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "status [project]",
+		Short: "Show whether the project is ready to release",
+		Long: `Show where the project stands without starting a release:
+
+  - Config file and validation result
+  - Version in tobrew.lock or Cargo.toml vs. the latest local and remote tag
+  - Commits since the last release
+  - Working tree cleanliness
+  - Whether each tap's formula matches the latest tag
+  - SHA256 drift between tobrew.lock and the published formula
+
+Exits with an error if any problem was found.
+
+Example:
+  tobrew status
+  tobrew status foo      # Project "foo" of a multi-project config
+  tobrew status --json   # Machine-readable output`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Problems are reported above, usage would only bury them
+			cmd.SilenceUsage = true
+			return runStatus(args, jsonOutput)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print status as JSON")
+
+	return cmd
+}
+
+func runStatus(args []string, jsonOutput bool) error {
+	status := collectStatus(args)
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(status); err != nil {
+			return err
+		}
+	} else {
+		printStatus(status)
+	}
+
+	if !status.Ready {
+		return fmt.Errorf("not ready to release: %d problem(s) found", len(status.Problems))
+	}
+	return nil
+}
+
+// collectStatus gathers the release status. Failures are recorded as
+// problems instead of aborting, so that as much as possible is reported.
+func collectStatus(args []string) *releaseStatus {
+	status := &releaseStatus{Config: "tobrew.yaml", Problems: []string{}}
+	if len(args) > 0 {
+		status.Project = args[0]
+	}
+
+	cfg, project, err := loadProjectConfig(args)
+	if err != nil {
+		status.ConfigError = err.Error()
+		status.Problems = append(status.Problems, "config: "+err.Error())
+		return status
+	}
+	status.ConfigValid = true
+
+	tagFormat := cfg.GetTagFormat()
+
+	lock := &version.Lock{}
+	if lockFile, err := version.LoadLock(); err != nil {
+		status.Problems = append(status.Problems, "lock file: "+err.Error())
+	} else {
+		lock = lockFile.Entry(project, tagFormat)
+	}
+
+	// Versions
+	if cfg.VersionFromGit() {
+		status.VersionSource = config.VersionSourceGit
+	} else if cfg.VersionFromCargo() {
+		status.VersionSource = config.VersionSourceCargo
+		if manifest, err := cargo.ReadManifest(cfg.CargoManifest()); err != nil {
+			status.Problems = append(status.Problems, "Cargo.toml: "+err.Error())
+		} else {
+			status.CargoVersion = manifest.Version
+		}
+	} else {
+		status.VersionSource = config.VersionSourceLock
+		status.LockVersion = lock.Version
+	}
+
+	// Without matching tags, the lookups return the 0.0.0 starting point
+	noTag := tagFormat.Format("0.0.0")
+	if status.LocalTag, err = getLatestLocalTag(tagFormat); err != nil {
+		status.Problems = append(status.Problems, "local tags: "+err.Error())
+	}
+	if status.LocalTag == noTag {
+		status.LocalTag = ""
+	}
+	if status.RemoteTag, err = getLatestRemoteTag(tagFormat); err != nil {
+		status.Problems = append(status.Problems, "remote tags: "+err.Error())
+	}
+	if status.RemoteTag == noTag {
+		status.RemoteTag = ""
+	}
+
+	if status.LockVersion != "" && status.RemoteTag != "" &&
+		version.Compare(tagFormat.Version(status.RemoteTag), tagFormat.Version(status.LockVersion)) > 0 {
+		status.Problems = append(status.Problems, fmt.Sprintf("tobrew.lock (%s) is behind origin (%s), run 'tobrew sync'", status.LockVersion, status.RemoteTag))
+	}
+	if status.CargoVersion != "" && status.RemoteTag != "" &&
+		version.Compare(tagFormat.Version(status.RemoteTag), status.CargoVersion) > 0 {
+		status.Problems = append(status.Problems, fmt.Sprintf("%s (%s) is behind origin (%s)", cfg.CargoManifest(), status.CargoVersion, status.RemoteTag))
+	}
+	if status.LocalTag != "" && status.RemoteTag != "" &&
+		version.Compare(tagFormat.Version(status.LocalTag), tagFormat.Version(status.RemoteTag)) > 0 {
+		status.Problems = append(status.Problems, fmt.Sprintf("local tag %s was never pushed to origin", status.LocalTag))
+	}
+
+	// Working tree. Commits are counted from the last release on origin,
+	// or from the local tag when origin has none or can't be read.
+	since := status.RemoteTag
+	if since == "" {
+		since = status.LocalTag
+	}
+	status.CommitsSince = commitsSince(since)
+	status.Clean = !hasUncommittedChanges()
+	if !status.Clean {
+		status.Problems = append(status.Problems, "working tree has uncommitted changes")
+	}
+
+	// Published formulas
+	lockSHA := lock.SHA256
+	if len(cfg.Rust.Targets) > 0 {
+		// Their formulas hold the checksums of the binary archives, not the source
+		lockSHA = ""
+	}
+	for _, tap := range cfg.GetTaps() {
+		status.Taps = append(status.Taps, checkTap(cfg, tap, status.RemoteTag, lockSHA, tagFormat))
+	}
+	for _, t := range status.Taps {
+		switch {
+		case t.Error != "":
+			status.Problems = append(status.Problems, fmt.Sprintf("tap %s: %s", t.Tap, t.Error))
+		case !t.UpToDate:
+			status.Problems = append(status.Problems, fmt.Sprintf("tap %s: formula version %s does not match %s", t.Tap, orNone(t.FormulaVersion), orNone(status.RemoteTag)))
+		case !t.SHA256Matches && lockSHA != "":
+			status.Problems = append(status.Problems, fmt.Sprintf("tap %s: formula sha256 differs from tobrew.lock", t.Tap))
+		}
+	}
+
+	status.Ready = len(status.Problems) == 0
+	return status
+}
+
+// checkTap compares the formula published in tap with the latest tag and the locked checksum
+func checkTap(cfg *config.Config, tap config.TapConfig, latestTag, lockSHA string, format *version.TagFormat) tapStatus {
+	t := tapStatus{Tap: tap.String()}
+
+	content, err := github.ReadFormula(cfg, tap)
+	if err != nil {
+		t.Error = err.Error()
+		return t
+	}
+	if content == "" {
+		// Nothing released yet is only a problem once a tag exists
+		t.UpToDate = latestTag == ""
+		t.SHA256Matches = lockSHA == ""
+		return t
+	}
+
+	if v, ok := formula.ParseVersion(content); ok {
+		t.FormulaVersion = v
+		t.UpToDate = strings.TrimPrefix(v, "v") == format.Version(latestTag)
+	}
+	if sum, ok := formula.ParseSHA256(content); ok {
+		t.SHA256 = sum
+		t.SHA256Matches = sum == lockSHA
+	}
+	return t
+}
+
+// commitsSince returns the number of commits on HEAD after tag, or nil if
+// the tag is not present locally
+func commitsSince(tag string) *int {
+	if tag == "" || !tagExists(tag) {
+		return nil
+	}
+	output, err := exec.Command("git", "rev-list", "--count", tag+"..HEAD").Output()
+	if err != nil {
+		return nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func printStatus(s *releaseStatus) {
+	fmt.Printf("Config:   %s", s.Config)
+	if s.ConfigValid {
+		fmt.Println(" ✓ valid")
+	} else {
+		fmt.Printf(" ✗ %s\n", s.ConfigError)
+	}
+	if s.Project != "" {
+		fmt.Printf("Project:  %s\n", s.Project)
+	}
+
+	if s.ConfigValid {
+		fmt.Printf("Source:   %s\n", s.VersionSource)
+		if s.VersionSource == config.VersionSourceLock {
+			fmt.Printf("Lock:     %s\n", orNone(s.LockVersion))
+		}
+		if s.VersionSource == config.VersionSourceCargo {
+			fmt.Printf("Cargo:    %s\n", orNone(s.CargoVersion))
+		}
+		fmt.Printf("Local:    %s\n", orNone(s.LocalTag))
+		fmt.Printf("Remote:   %s\n", orNone(s.RemoteTag))
+		if s.CommitsSince != nil {
+			fmt.Printf("Commits:  %d since %s\n", *s.CommitsSince, firstNonEmpty(s.RemoteTag, s.LocalTag))
+		} else {
+			fmt.Println("Commits:  unknown, no release tag present locally")
+		}
+		if s.Clean {
+			fmt.Println("Tree:     clean")
+		} else {
+			fmt.Println("Tree:     uncommitted changes")
+		}
+
+		if len(s.Taps) > 0 {
+			fmt.Println("Taps:")
+			for _, t := range s.Taps {
+				switch {
+				case t.Error != "":
+					fmt.Printf("  ✗ %s: %s\n", t.Tap, t.Error)
+				case t.FormulaVersion == "" && t.SHA256 == "":
+					fmt.Printf("  - %s: no formula published\n", t.Tap)
+				default:
+					mark := "✓"
+					if !t.UpToDate || !t.SHA256Matches {
+						mark = "✗"
+					}
+					fmt.Printf("  %s %s: %s (sha256 %s)\n", mark, t.Tap, orNone(t.FormulaVersion), shortSHA(t.SHA256))
+				}
+			}
+		}
+	}
+
+	fmt.Println()
+	if s.Ready {
+		fmt.Println("✅ Ready to release")
+		return
+	}
+	fmt.Println("⚠️  Problems:")
+	for _, p := range s.Problems {
+		fmt.Printf("  - %s\n", p)
+	}
+}
+
+// orNone returns value, or "(none)" if it is empty
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -9,7 +9,7 @@ import (
 
 func SyncCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "sync",
+		Use:   "sync [project]",
 		Short: "Sync lock file with remote tags",
 		Long: `Sync the local tobrew.lock file with remote git tags.
 
@@ -19,9 +19,12 @@ This is useful when:
   - Recovering from a failed release
 
 The command will:
-  1. Fetch remote tags
+  1. Read tags on origin (git ls-remote)
   2. Find the latest version tag
-  3. Update tobrew.lock with the latest version`,
+  3. Update tobrew.lock with the latest version
+
+In multi-project configs, pass the project whose lock entry to sync.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: runSync,
 	}
 
@@ -29,18 +32,34 @@ The command will:
 }
 
 func runSync(cmd *cobra.Command, args []string) error {
+	cfg, project, err := loadProjectConfig(args)
+	if err != nil {
+		return err
+	}
+
+	if cfg.VersionFromGit() {
+		fmt.Println("✓ Nothing to sync: the version is read from git tags (version.source: git)")
+		return nil
+	}
+	if cfg.VersionFromCargo() {
+		fmt.Printf("✓ Nothing to sync: the version is read from %s (version.source: cargo)\n", cfg.CargoManifest())
+		return nil
+	}
+
 	// Load lock file
-	lock, err := version.LoadLock()
+	lockFile, err := version.LoadLock()
 	if err != nil {
 		return fmt.Errorf("failed to load lock file: %w", err)
 	}
+	tagFormat := cfg.GetTagFormat()
+	lock := lockFile.Entry(project, tagFormat)
 
 	currentVersion := lock.Version
 	fmt.Printf("📋 Current lock version: %s\n", currentVersion)
 
 	// Get latest remote tag
-	fmt.Println("🔄 Fetching remote tags...")
-	latestTag, err := getLatestRemoteTag()
+	fmt.Println("🔄 Reading remote tags...")
+	latestTag, err := getLatestRemoteTag(tagFormat)
 	if err != nil {
 		return fmt.Errorf("failed to get remote tags: %w", err)
 	}
@@ -48,16 +67,15 @@ func runSync(cmd *cobra.Command, args []string) error {
 	fmt.Printf("   Latest remote tag: %s\n", latestTag)
 
 	// Compare and update
-	if compareVersions(latestTag, currentVersion) > 0 {
+	if version.Compare(tagFormat.Version(latestTag), tagFormat.Version(currentVersion)) > 0 {
 		lock.Version = latestTag
-		lock.UpdateFingerprint()
-		if err := lock.Save(); err != nil {
+		if err := lockFile.Save(); err != nil {
 			return fmt.Errorf("failed to save lock file: %w", err)
 		}
 		fmt.Printf("\n✅ Lock file updated: %s → %s\n", currentVersion, latestTag)
-	} else if compareVersions(latestTag, currentVersion) == 0 {
-		lock.UpdateFingerprint()
-		if err := lock.Save(); err != nil {
+	} else if version.Compare(tagFormat.Version(latestTag), tagFormat.Version(currentVersion)) == 0 {
+		// Saving drops fields older tobrew versions wrote, like the machine fingerprint
+		if err := lockFile.Save(); err != nil {
 			return fmt.Errorf("failed to save lock file: %w", err)
 		}
 		fmt.Println("\n✅ Already in sync")
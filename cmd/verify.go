@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/version"
+)
+
+func VerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [project] <version>",
+		Short: "Re-download a past release and check its recorded checksum",
+		Long: `Re-download the source tarball of a past release and compare its
+SHA256 with the checksum recorded in tobrew.lock. The tag on origin is
+also checked to still point at the recorded commit.
+
+Example:
+  tobrew verify v1.2.3
+  tobrew verify foo 1.2.3   # Project "foo" of a multi-project config`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: runVerify,
+	}
+
+	return cmd
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	query := args[len(args)-1]
+	cfg, project, err := loadProjectConfig(args[:len(args)-1])
+	if err != nil {
+		return err
+	}
+
+	lockFile, err := version.LoadLock()
+	if err != nil {
+		return fmt.Errorf("failed to load lock file: %w", err)
+	}
+	tagFormat := cfg.GetTagFormat()
+	release := lockFile.Entry(project, tagFormat).FindRelease(query, tagFormat)
+	if release == nil {
+		return fmt.Errorf("release %s is not recorded in tobrew.lock", query)
+	}
+	if release.SHA256 == "" {
+		return fmt.Errorf("release %s has no recorded checksum", release.Version)
+	}
+
+	fmt.Printf("🔍 Verifying %s (released %s)\n", release.Version, release.Date.Format("2006-01-02"))
+	failed := false
+
+	// The tag must not have been moved since the release. Origin is asked,
+	// a fetch wouldn't update a local tag that was moved there.
+	if release.Commit != "" {
+		var commit string
+		if exec.Command("git", "remote", "get-url", "origin").Run() == nil {
+			commit, err = remoteTagCommit(release.Version)
+			if err != nil {
+				return fmt.Errorf("failed to read tag %s from origin: %w", release.Version, err)
+			}
+		} else {
+			fmt.Println("⚠️  No origin remote, checking the local tag")
+			commit = tagCommit(release.Version)
+		}
+		switch {
+		case commit == "":
+			fmt.Printf("✗ Tag %s not found\n", release.Version)
+			failed = true
+		case commit != release.Commit:
+			fmt.Printf("✗ Tag %s points to %s, recorded %s\n", release.Version, shortSHA(commit), shortSHA(release.Commit))
+			failed = true
+		default:
+			fmt.Printf("✓ Tag points to recorded commit %s\n", shortSHA(commit))
+		}
+	}
+
+	url := release.URL
+	if url == "" {
+		url = cfg.GetTarballURL(release.Version)
+	}
+	fmt.Printf("   Downloading %s\n", url)
+	sha256sum, err := downloadAndHash(cfg, url)
+	if err != nil {
+		return fmt.Errorf("failed to download/hash tarball: %w", err)
+	}
+	if sha256sum != release.SHA256 {
+		fmt.Printf("✗ SHA256 mismatch\n   recorded: %s\n   actual:   %s\n", release.SHA256, sha256sum)
+		failed = true
+	} else {
+		fmt.Printf("✓ SHA256 matches: %s\n", sha256sum)
+	}
+
+	if failed {
+		return fmt.Errorf("verification of %s failed", release.Version)
+	}
+	fmt.Printf("\n✅ %s verified\n", release.Version)
+	return nil
+}
+
+// remoteTagCommit returns the commit a tag points to on origin, or "" if
+// origin has no such tag
+func remoteTagCommit(tag string) (string, error) {
+	output, err := lsRemote("origin", "refs/tags/"+tag+"^{}", "refs/tags/"+tag)
+	if err != nil {
+		return "", err
+	}
+
+	// Annotated tags are listed twice, the peeled ^{} line names the commit
+	commit := ""
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		sha, ref, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		switch ref {
+		case "refs/tags/" + tag + "^{}":
+			return sha, nil
+		case "refs/tags/" + tag:
+			commit = sha
+		}
+	}
+	return commit, nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yejune/tobrew/internal/cargo"
+	"github.com/yejune/tobrew/internal/version"
+)
+
+func VersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version [project]",
+		Short: "Show the current and next release versions",
+		Long: `Show the current release version and the versions the next
+patch, minor and major release would create.
+
+The current version comes from tobrew.lock, or from the highest
+matching git tag when version.source is git.
+
+Example:
+  tobrew version
+  tobrew version foo     # Project "foo" of a multi-project config`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runVersion,
+	}
+
+	return cmd
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	cfg, project, err := loadProjectConfig(args)
+	if err != nil {
+		return err
+	}
+
+	tagFormat := cfg.GetTagFormat()
+
+	var source, currentTag string
+	if cfg.VersionFromGit() {
+		source = "git tags"
+		currentTag, err = getLatestRemoteTag(tagFormat)
+		if err != nil {
+			return fmt.Errorf("failed to get latest remote tag: %w", err)
+		}
+	} else if cfg.VersionFromCargo() {
+		source = cfg.CargoManifest()
+		manifest, err := cargo.ReadManifest(cfg.CargoManifest())
+		if err != nil {
+			return err
+		}
+		currentTag = tagFormat.Format(manifest.Version)
+	} else {
+		source = "tobrew.lock"
+		lockFile, err := version.LoadLock()
+		if err != nil {
+			return fmt.Errorf("failed to load lock file: %w", err)
+		}
+		currentTag = lockFile.Entry(project, tagFormat).Version
+	}
+
+	fmt.Printf("Source:  %s\n", source)
+	fmt.Printf("Current: %s\n", currentTag)
+	fmt.Println("Next:")
+	for _, bump := range []struct {
+		name string
+		typ  version.BumpType
+	}{
+		{"patch", version.BumpPatch},
+		{"minor", version.BumpMinor},
+		{"major", version.BumpMajor},
+	} {
+		next, err := version.Next(currentTag, bump.typ, tagFormat)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %-6s %s\n", bump.name, next)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/forge"
+)
+
+// defaultTokenEnvs lists the conventional token variables of each forge
+var defaultTokenEnvs = map[string][]string{
+	forge.TypeGitHub: {"GITHUB_TOKEN", "GH_TOKEN"},
+	forge.TypeGitLab: {"GITLAB_TOKEN"},
+	forge.TypeGitea:  {"GITEA_TOKEN"},
+}
+
+// Token returns the API token for a forge. When envName is set only that
+// variable is read, otherwise the forge's conventional variables are tried.
+func Token(envName string, forgeType string) string {
+	for _, name := range TokenEnvs(envName, forgeType) {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// TokenEnvs returns the variables Token reads, in order
+func TokenEnvs(envName string, forgeType string) []string {
+	if envName != "" {
+		return []string{envName}
+	}
+	if forgeType == "" {
+		forgeType = forge.TypeGitHub
+	}
+	return defaultTokenEnvs[forgeType]
+}
+
+// Get performs an HTTP GET, authorized with token when url belongs to f
+func Get(f forge.Forge, token string, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	f.Authorize(req, token)
+	return http.DefaultClient.Do(req)
+}
+
+// Scopes checks token against the forge API and returns the scopes granted
+// to it. A nil slice with a nil error means the token is valid but the forge
+// does not report scopes, e.g. GitHub fine-grained tokens or Gitea.
+func Scopes(f forge.Forge, token string) ([]string, error) {
+	apiURL := f.TokenAPIURL()
+	if apiURL == "" {
+		return nil, fmt.Errorf("%s forges have no API to check tokens", f.Type())
+	}
+
+	resp, err := Get(f, token, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token rejected: HTTP %d", resp.StatusCode)
+	}
+
+	switch f.Type() {
+	case forge.TypeGitHub:
+		// Classic tokens list their scopes in a header
+		header := resp.Header.Get("X-OAuth-Scopes")
+		if header == "" {
+			return nil, nil
+		}
+		var scopes []string
+		for _, scope := range strings.Split(header, ",") {
+			scopes = append(scopes, strings.TrimSpace(scope))
+		}
+		return scopes, nil
+
+	case forge.TypeGitLab:
+		var info struct {
+			Scopes []string `json:"scopes"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return nil, fmt.Errorf("failed to parse token info: %w", err)
+		}
+		return info.Scopes, nil
+	}
+
+	return nil, nil
+}
+
+// GitEnv returns the environment for git commands talking to rawURL. The
+// token is passed as an http.extraHeader through GIT_CONFIG_* variables,
+// so it is never written to .git/config or visible in the process list.
+// Without a token, or for URLs outside the forge (SSH, file://), it
+// returns nil and git keeps using its own credential helpers.
+func GitEnv(f forge.Forge, token string, rawURL string) []string {
+	header := f.GitAuthHeader(rawURL, token)
+	if header == "" {
+		return nil
+	}
+
+	// Keep config entries already passed through the environment
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	return append(os.Environ(),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s.extraHeader", n, rawURL),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: %s", n, header),
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+	)
+}
@@ -0,0 +1,178 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Manifest is the package metadata of a Cargo.toml. Fields inherited from
+// the workspace ({workspace = true}) are empty.
+type Manifest struct {
+	Name        string
+	Version     string
+	Description string
+	License     string // SPDX expression; the old a/b form becomes a OR b
+	Homepage    string
+	Repository  string
+
+	// VersionFromWorkspace is set when the version is inherited from the
+	// workspace manifest
+	VersionFromWorkspace bool
+}
+
+// ReadManifest reads the [package] table of a Cargo.toml
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseManifest parses the [package] table of a Cargo.toml
+func ParseManifest(data []byte) (*Manifest, error) {
+	var cargo struct {
+		Package map[string]interface{} `toml:"package"`
+	}
+	if err := toml.Unmarshal(data, &cargo); err != nil {
+		return nil, err
+	}
+
+	field := func(name string) string {
+		s, _ := cargo.Package[name].(string)
+		return s
+	}
+	_, inherited := cargo.Package["version"].(map[string]interface{})
+	return &Manifest{
+		Name:                 field("name"),
+		Version:              field("version"),
+		Description:          field("description"),
+		License:              strings.ReplaceAll(field("license"), "/", " OR "),
+		Homepage:             field("homepage"),
+		Repository:           field("repository"),
+		VersionFromWorkspace: inherited,
+	}, nil
+}
+
+// SetVersion writes version to the [package] table of the Cargo.toml at
+// manifestPath and to the crate's entry in Cargo.lock, which is looked for
+// next to the manifest and then in the directories above it (workspaces
+// share one). Only the version strings change. It returns the files written.
+func SetVersion(manifestPath, version string) ([]string, error) {
+	m, err := ReadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if m.VersionFromWorkspace {
+		return nil, fmt.Errorf("%s inherits its version from the workspace, which tobrew can't bump", manifestPath)
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	updated, ok := setTableVersion(string(data), func(header string, _ []string) bool {
+		return header == "[package]"
+	}, version)
+	if !ok {
+		return nil, fmt.Errorf("%s has no version in [package]", manifestPath)
+	}
+	if err := os.WriteFile(manifestPath, []byte(updated), 0644); err != nil {
+		return nil, err
+	}
+	files := []string{manifestPath}
+
+	lockPath := findLock(filepath.Dir(manifestPath))
+	if lockPath == "" {
+		return files, nil
+	}
+	data, err = os.ReadFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	name := regexp.MustCompile(`^name\s*=\s*"` + regexp.QuoteMeta(m.Name) + `"`)
+	updated, ok = setTableVersion(string(data), func(header string, lines []string) bool {
+		if header != "[[package]]" {
+			return false
+		}
+		for _, line := range lines {
+			if name.MatchString(strings.TrimSpace(line)) {
+				return true
+			}
+		}
+		return false
+	}, version)
+	if !ok {
+		// A lock that doesn't list the crate yet, cargo adds it on the next build
+		return files, nil
+	}
+	if err := os.WriteFile(lockPath, []byte(updated), 0644); err != nil {
+		return nil, err
+	}
+	return append(files, lockPath), nil
+}
+
+// VersionFiles returns the files SetVersion may write: the manifest and the
+// Cargo.lock it shares, if there is one
+func VersionFiles(manifestPath string) []string {
+	files := []string{manifestPath}
+	if lockPath := findLock(filepath.Dir(manifestPath)); lockPath != "" {
+		files = append(files, lockPath)
+	}
+	return files
+}
+
+// versionRe matches a version key line, keeping everything around the value
+var versionRe = regexp.MustCompile(`^(\s*version\s*=\s*)"[^"]*"(.*)$`)
+
+// setTableVersion replaces the version value of the first table match
+// accepts, given its header and lines. It reports whether one was replaced.
+func setTableVersion(content string, match func(header string, lines []string) bool, version string) (string, bool) {
+	lines := strings.Split(content, "\n")
+
+	// Tables run from their header to the next one
+	start := -1
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "[") {
+			continue
+		}
+		if start >= 0 && match(strings.TrimSpace(lines[start]), lines[start+1:i]) {
+			for j := start + 1; j < i; j++ {
+				if m := versionRe.FindStringSubmatch(lines[j]); m != nil {
+					lines[j] = m[1] + `"` + version + `"` + m[2]
+					return strings.Join(lines, "\n"), true
+				}
+			}
+		}
+		start = i
+	}
+	return content, false
+}
+
+// findLock returns the Cargo.lock of a crate directory or of the workspace
+// above it, up to the root of the git repository, or "" if there is none
+func findLock(dir string) string {
+	for {
+		path := filepath.Join(dir, "Cargo.lock")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -3,67 +3,167 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
-	"gopkg.in/yaml.v3"
+	"github.com/yejune/tobrew/internal/auth"
+	"github.com/yejune/tobrew/internal/forge"
+	"github.com/yejune/tobrew/internal/version"
 )
 
 // Config represents the tobrew configuration file
 type Config struct {
-	Name        string        `yaml:"name"`
-	Language    string        `yaml:"language,omitempty"` // go, rust, python, node, php, binary
-	Description string        `yaml:"description"`
-	Homepage    string        `yaml:"homepage"`
-	License     string        `yaml:"license"`
-	GitHub      GitHubConfig  `yaml:"github"`
-	Build       BuildConfig   `yaml:"build"`
-	Formula     FormulaConfig `yaml:"formula"`
+	Schema        string `yaml:"$schema,omitempty" json:"$schema,omitempty" toml:"-"`                                      // JSON Schema for editors, ignored by tobrew
+	SchemaVersion int    `yaml:"schema_version,omitempty" json:"schema_version,omitempty" toml:"schema_version,omitempty"` // config file format, see SchemaVersion
+	Extends       string `yaml:"extends,omitempty" json:"extends,omitempty" toml:"extends,omitempty"`                      // file or URL of shared defaults this config is merged onto
+
+	Name        string        `yaml:"name" json:"name" toml:"name"`
+	Language    string        `yaml:"language,omitempty" json:"language,omitempty" toml:"language,omitempty"` // go, rust, python, node, php, binary
+	Description string        `yaml:"description" json:"description" toml:"description"`
+	Homepage    string        `yaml:"homepage" json:"homepage" toml:"homepage"`
+	License     string        `yaml:"license" json:"license" toml:"license"`
+	Forge       ForgeConfig   `yaml:"forge,omitempty" json:"forge,omitempty" toml:"forge,omitempty"`
+	GitHub      GitHubConfig  `yaml:"github" json:"github" toml:"github"`
+	Build       BuildConfig   `yaml:"build" json:"build" toml:"build"`
+	Formula     FormulaConfig `yaml:"formula" json:"formula" toml:"formula"`
+	Taps        []TapConfig   `yaml:"taps,omitempty" json:"taps,omitempty" toml:"taps,omitempty"`
+	Rust        RustConfig    `yaml:"rust,omitempty" json:"rust,omitempty" toml:"rust,omitempty"`
+
+	Version   VersionConfig   `yaml:"version,omitempty" json:"version,omitempty" toml:"version,omitempty"`
+	TagPrefix string          `yaml:"-" json:"-" toml:"-"`                                                    // prepended to release tags of a project, e.g. "mytool/"
+	Projects  []ProjectConfig `yaml:"projects,omitempty" json:"projects,omitempty" toml:"projects,omitempty"` // formulas released from this repository
+}
+
+// VersionConfig controls where the current version comes from and how it maps to git tags
+type VersionConfig struct {
+	TagFormat string `yaml:"tag_format,omitempty" json:"tag_format,omitempty" toml:"tag_format,omitempty"` // default: v{{.Version}}
+	Source    string `yaml:"source,omitempty" json:"source,omitempty" toml:"source,omitempty"`             // lock (default), git or cargo
+	Lock      string `yaml:"lock,omitempty" json:"lock,omitempty" toml:"lock,omitempty"`                   // with source git: record (default) or none
+
+	BehindRemote string `yaml:"behind_remote,omitempty" json:"behind_remote,omitempty" toml:"behind_remote,omitempty"` // lock behind origin's tags: sync (default) or fail
+}
+
+// Version sources
+const (
+	VersionSourceLock = "lock" // tobrew.lock holds the current version
+	VersionSourceGit  = "git"  // the highest matching git tag is the current version
+
+	// Cargo.toml holds the current version, releases write the new one to
+	// it and Cargo.lock
+	VersionSourceCargo = "cargo"
+)
+
+// Policies for a lock version behind the tags on origin
+const (
+	BehindRemoteSync = "sync" // continue from the latest remote tag
+	BehindRemoteFail = "fail" // refuse to release until tobrew sync is run
+)
+
+// Lock file modes for the git version source
+const (
+	LockRecord = "record" // tobrew.lock is written as a record of releases
+	LockNone   = "none"   // tobrew.lock is not written
+)
+
+// ForgeConfig selects the git hosting service of the project and its taps
+type ForgeConfig struct {
+	Type       string `yaml:"type,omitempty" json:"type,omitempty" toml:"type,omitempty"`                      // github (default), gitlab, gitea, generic
+	URL        string `yaml:"url,omitempty" json:"url,omitempty" toml:"url,omitempty"`                         // base URL of a self-hosted instance
+	TarballURL string `yaml:"tarball_url,omitempty" json:"tarball_url,omitempty" toml:"tarball_url,omitempty"` // tarball URL template, generic forges only
 }
 
+// GitHubConfig holds the repository coordinates, on whichever forge is configured
 type GitHubConfig struct {
-	User    string `yaml:"user"`
-	Repo    string `yaml:"repo"`
-	TapRepo string `yaml:"tap_repo"`
+	User    string `yaml:"user" json:"user" toml:"user"`
+	Repo    string `yaml:"repo" json:"repo" toml:"repo"`
+	TapRepo string `yaml:"tap_repo" json:"tap_repo" toml:"tap_repo"`
+	TapURL  string `yaml:"tap_url,omitempty" json:"tap_url,omitempty" toml:"tap_url,omitempty"` // explicit clone URL (https, ssh or file://)
+
+	TapProtocol string `yaml:"tap_protocol,omitempty" json:"tap_protocol,omitempty" toml:"tap_protocol,omitempty"` // https (default) or ssh
+	TokenEnv    string `yaml:"token_env,omitempty" json:"token_env,omitempty" toml:"token_env,omitempty"`          // token variable, default GITHUB_TOKEN/GH_TOKEN
+	Private     bool   `yaml:"private,omitempty" json:"private,omitempty" toml:"private,omitempty"`                // download sources through the authenticated API
+}
+
+// TapConfig describes a Homebrew tap repository the formula is published to
+type TapConfig struct {
+	Owner     string `yaml:"owner,omitempty" json:"owner,omitempty" toml:"owner,omitempty"`             // default: github.user
+	Repo      string `yaml:"repo" json:"repo" toml:"repo"`                                              // e.g. homebrew-tap
+	Branch    string `yaml:"branch,omitempty" json:"branch,omitempty" toml:"branch,omitempty"`          // default: main
+	Directory string `yaml:"directory,omitempty" json:"directory,omitempty" toml:"directory,omitempty"` // formula directory inside the tap, e.g. Formula
+	Mode      string `yaml:"mode,omitempty" json:"mode,omitempty" toml:"mode,omitempty"`                // push (default) or branch
+	URL       string `yaml:"url,omitempty" json:"url,omitempty" toml:"url,omitempty"`                   // explicit clone URL (https, ssh or file://)
+	Protocol  string `yaml:"protocol,omitempty" json:"protocol,omitempty" toml:"protocol,omitempty"`    // https or ssh, default: github.tap_protocol
+}
+
+// Tap update modes
+const (
+	TapModePush   = "push"   // commit directly to the tap branch
+	TapModeBranch = "branch" // push to a tobrew/<name>-<version> branch for review
+)
+
+// Tap clone protocols
+const (
+	ProtocolHTTPS = "https"
+	ProtocolSSH   = "ssh"
+)
+
+// RustConfig holds the settings of rust projects
+type RustConfig struct {
+	Manifest string   `yaml:"manifest,omitempty" json:"manifest,omitempty" toml:"manifest,omitempty"` // Cargo.toml of the crate, default: Cargo.toml
+	Targets  []string `yaml:"targets,omitempty" json:"targets,omitempty" toml:"targets,omitempty"`    // target triples to publish binary archives for
+	Binary   string   `yaml:"binary,omitempty" json:"binary,omitempty" toml:"binary,omitempty"`       // binary in the archives, default: name
 }
 
 type BuildConfig struct {
-	Command string `yaml:"command"`
+	Command string `yaml:"command" json:"command" toml:"command"`
 }
 
 type FormulaConfig struct {
-	Install string `yaml:"install"`
-	Test    string `yaml:"test"`
-	Caveats string `yaml:"caveats"`
+	Install string `yaml:"install" json:"install" toml:"install"`
+	Test    string `yaml:"test" json:"test" toml:"test"`
+	Caveats string `yaml:"caveats" json:"caveats" toml:"caveats"`
+
+	// Files holding the scripts above, relative to the config file
+	InstallFile string `yaml:"install_file,omitempty" json:"install_file,omitempty" toml:"install_file,omitempty"`
+	TestFile    string `yaml:"test_file,omitempty" json:"test_file,omitempty" toml:"test_file,omitempty"`
+	CaveatsFile string `yaml:"caveats_file,omitempty" json:"caveats_file,omitempty" toml:"caveats_file,omitempty"`
+
+	// Dependencies besides the language's own, e.g. openssl@3
+	Dependencies []Dependency `yaml:"dependencies,omitempty" json:"dependencies,omitempty" toml:"dependencies,omitempty"`
+}
+
+// Dependency is a depends_on line of the formula
+type Dependency struct {
+	Name string `yaml:"name" json:"name" toml:"name"`
+	Type string `yaml:"type,omitempty" json:"type,omitempty" toml:"type,omitempty"` // build, test, optional or recommended; default: runtime
 }
 
-// Load reads and parses the tobrew.yaml config file
+// Dependency types
+var DependencyTypes = []string{"build", "test", "optional", "recommended"}
+
+// Load reads and parses the tobrew.yaml config file. It is merged onto the
+// config it extends, ${VAR} references and !include tags are expanded, and
+// formula *_file scripts are read.
 func Load(path string) (*Config, error) {
 	if path == "" {
 		path = "tobrew.yaml"
 	}
 
-	data, err := os.ReadFile(path)
+	doc, ps, err := readConfig(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := doc.root.Decode(&config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	ps = append(ps, config.readFiles()...)
+	ps = append(ps, config.readCargo()...)
 
-	// Validate required fields
-	if config.Name == "" {
-		return nil, fmt.Errorf("name is required")
-	}
-	if config.GitHub.User == "" {
-		return nil, fmt.Errorf("github.user is required")
-	}
-	if config.GitHub.Repo == "" {
-		return nil, fmt.Errorf("github.repo is required")
-	}
-	if config.GitHub.TapRepo == "" {
-		return nil, fmt.Errorf("github.tap_repo is required")
+	if err := append(ps, config.problems()...).err(); err != nil {
+		return nil, err
 	}
 
 	// Default language to "go" if not specified
@@ -74,34 +174,139 @@ func Load(path string) (*Config, error) {
 	return &config, nil
 }
 
-// Save writes the config to a file
-func (c *Config) Save(path string) error {
-	if path == "" {
-		path = "tobrew.yaml"
+// GetForge returns the forge hosting the project
+func (c *Config) GetForge() forge.Forge {
+	f, err := forge.New(c.Forge.Type, c.Forge.URL, c.Forge.TarballURL)
+	if err != nil {
+		// Load rejects invalid forge settings; unvalidated configs fall back to GitHub
+		f, _ = forge.New(forge.TypeGitHub, "", "")
 	}
+	return f
+}
 
-	data, err := yaml.Marshal(c)
+// GetTarballURL returns the source tarball URL for a tag.
+// Private repositories use the forge API, which accepts token authentication.
+func (c *Config) GetTarballURL(tag string) string {
+	if c.GitHub.Private {
+		return c.GetForge().ArchiveAPIURL(c.GitHub.User, c.GitHub.Repo, tag)
+	}
+	return c.GetForge().TarballURL(c.GitHub.User, c.GitHub.Repo, tag, c.GetTagFormat().Version(tag))
+}
+
+// GetRepoURL returns the clone URL of the project repository
+func (c *Config) GetRepoURL() string {
+	return c.GetForge().CloneURL(c.GitHub.User, c.GitHub.Repo)
+}
+
+// GetReleaseAPIURL returns the forge API endpoint of a release, or "" if unsupported
+func (c *Config) GetReleaseAPIURL(version string) string {
+	return c.GetForge().ReleaseAPIURL(c.GitHub.User, c.GitHub.Repo, version)
+}
+
+// tagFormat returns the effective tag format template, including a project's tag_prefix
+func (c *Config) tagFormat() string {
+	format := c.Version.TagFormat
+	if format == "" {
+		format = version.DefaultTagFormat
+	}
+	return c.TagPrefix + format
+}
+
+// GetTagFormat returns the format used to create and parse release tags
+func (c *Config) GetTagFormat() *version.TagFormat {
+	format, err := version.ParseTagFormat(c.tagFormat())
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		// Load rejects invalid formats; unvalidated configs fall back to the default
+		format, _ = version.ParseTagFormat(version.DefaultTagFormat)
 	}
+	return format
+}
+
+// VersionFromCargo reports whether the current version is read from Cargo.toml
+func (c *Config) VersionFromCargo() bool {
+	return c.Version.Source == VersionSourceCargo
+}
+
+// VersionFromGit reports whether the current version is derived from git tags
+func (c *Config) VersionFromGit() bool {
+	return c.Version.Source == VersionSourceGit
+}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+// WritesLock reports whether releases are recorded in tobrew.lock
+func (c *Config) WritesLock() bool {
+	return c.Version.Lock != LockNone
+}
+
+// GetTaps returns the taps to publish to with defaults applied.
+// Without a taps list, the single github.tap_repo is used.
+func (c *Config) GetTaps() []TapConfig {
+	taps := c.Taps
+	if len(taps) == 0 {
+		taps = []TapConfig{{Repo: c.GitHub.TapRepo, URL: c.GitHub.TapURL}}
 	}
 
-	return nil
+	result := make([]TapConfig, 0, len(taps))
+	for _, tap := range taps {
+		if tap.Owner == "" {
+			tap.Owner = c.GitHub.User
+		}
+		if tap.Branch == "" {
+			tap.Branch = "main"
+		}
+		if tap.Mode == "" {
+			tap.Mode = TapModePush
+		}
+		if tap.Protocol == "" {
+			tap.Protocol = c.GitHub.TapProtocol
+		}
+		if tap.Protocol == "" {
+			tap.Protocol = ProtocolHTTPS
+		}
+		result = append(result, tap)
+	}
+	return result
+}
+
+// GetTapRepoURL returns the clone URL of a tap
+func (c *Config) GetTapRepoURL(tap TapConfig) string {
+	if tap.URL != "" {
+		return tap.URL
+	}
+	if tap.Protocol == ProtocolSSH {
+		return c.GetForge().SSHURL(tap.Owner, tap.Repo)
+	}
+	return c.GetForge().CloneURL(tap.Owner, tap.Repo)
+}
+
+// GetToken returns the forge API token from the environment, or "" if unset
+func (c *Config) GetToken() string {
+	return auth.Token(c.GitHub.TokenEnv, c.GetForge().Type())
+}
+
+// NeedsTapURL reports whether users must pass the tap URL to brew tap,
+// which is the case for every tap not hosted on github.com
+func (c *Config) NeedsTapURL(tap TapConfig) bool {
+	return !strings.HasPrefix(c.GetTapRepoURL(tap), "https://github.com/")
+}
+
+// validProtocol reports whether p is an accepted tap protocol (empty means default)
+func validProtocol(p string) bool {
+	return p == "" || p == ProtocolHTTPS || p == ProtocolSSH
+}
+
+// String returns the tap as owner/repo
+func (t TapConfig) String() string {
+	return t.Owner + "/" + t.Repo
 }
 
-// GetTarballURL returns the GitHub tarball URL for a version
-func (c *Config) GetTarballURL(version string) string {
-	return fmt.Sprintf("https://github.com/%s/%s/archive/refs/tags/%s.tar.gz",
-		c.GitHub.User, c.GitHub.Repo, version)
+// TapName returns the name used with brew tap, e.g. "user/tap" for user/homebrew-tap
+func (t TapConfig) TapName() string {
+	return t.Owner + "/" + strings.TrimPrefix(t.Repo, "homebrew-")
 }
 
-// GetTapRepoURL returns the GitHub tap repository URL
-func (c *Config) GetTapRepoURL() string {
-	return fmt.Sprintf("https://github.com/%s/%s.git",
-		c.GitHub.User, c.GitHub.TapRepo)
+// FormulaPath returns the formula file path relative to the tap root
+func (t TapConfig) FormulaPath(name string) string {
+	return path.Join(t.Directory, name+".rb")
 }
 
 // GetFormulaName returns the Ruby class name for the formula
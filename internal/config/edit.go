@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is a config file opened for editing. Edits replace only the values
+// they touch: comments, key order and the layout of everything else are
+// kept. YAML and JSON files are edited as yaml.Node trees, TOML files line
+// by line.
+type File struct {
+	path   string
+	format string // yaml, json or toml
+	data   []byte // contents as read
+	doc    *yaml.Node
+	lines  []string // TOML
+}
+
+// keyPart is one step of a dotted key path: a mapping key, optionally
+// followed by a list index, e.g. taps[0]
+type keyPart struct {
+	name  string
+	index int // -1 for none
+}
+
+// OpenFile reads a config file for editing. The format is taken from the
+// extension: .json and .toml, anything else is YAML.
+func OpenFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	f := &File{path: path, format: fileFormat(path), data: data}
+
+	if f.format == "toml" {
+		f.lines = strings.Split(string(data), "\n")
+		return f, nil
+	}
+
+	// JSON is YAML, and parsing it as such keeps the key order
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: config must be a mapping", path)
+	}
+	f.doc = &doc
+	return f, nil
+}
+
+// fileFormat returns the config format of a file name
+func fileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".toml":
+		return "toml"
+	}
+	return "yaml"
+}
+
+// root returns the top-level mapping of a YAML or JSON file
+func (f *File) root() *yaml.Node {
+	return f.doc.Content[0]
+}
+
+// Set sets a dotted key path such as "github.user" or "taps[0].repo" to
+// value. Missing mappings are created; a list index one past the end
+// appends an item.
+func (f *File) Set(key string, value interface{}) error {
+	parts, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	if f.format == "toml" {
+		return f.setTOML(key, parts, value)
+	}
+
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return setNode(f.root(), parts, &valueNode)
+}
+
+// Bytes returns the edited file
+func (f *File) Bytes() ([]byte, error) {
+	switch f.format {
+	case "toml":
+		return []byte(strings.Join(f.lines, "\n")), nil
+	case "json":
+		return encodeJSON(f.root(), f.data), nil
+	}
+	return encodeYAML(f.doc, f.data)
+}
+
+// Write saves the edited file
+func (f *File) Write() error {
+	data, err := f.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", f.path, err)
+	}
+	if err := os.WriteFile(f.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// splitKey parses a dotted key path such as "taps[0].repo"
+func splitKey(key string) ([]keyPart, error) {
+	if key == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+	var parts []keyPart
+	for _, s := range strings.Split(key, ".") {
+		part := keyPart{name: s, index: -1}
+		if open := strings.Index(s, "["); open >= 0 {
+			index, err := strconv.Atoi(strings.TrimSuffix(s[open+1:], "]"))
+			if err != nil || !strings.HasSuffix(s, "]") || index < 0 {
+				return nil, fmt.Errorf("invalid key %q: bad list index in %q", key, s)
+			}
+			part = keyPart{name: s[:open], index: index}
+		}
+		if part.name == "" {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// KeyType returns the Go type of a config key, e.g. string for
+// "github.user", or an error for keys the config doesn't have
+func KeyType(key string) (reflect.Type, error) {
+	parts, err := splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	typ := reflect.TypeOf(Config{})
+	for i, part := range parts {
+		field, ok := yamlFields(typ)[part.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", joinKey(keyString(parts[:i]), part.name))
+		}
+		typ = field.Type
+		if part.index >= 0 {
+			if typ.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("%s is not a list", keyString(parts[:i+1]))
+			}
+			typ = typ.Elem()
+		}
+		if i < len(parts)-1 && typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s has no keys", keyString(parts[:i+1]))
+		}
+	}
+	return typ, nil
+}
+
+// ParseValue converts the command-line form of a value to the type of key:
+// text for strings, and YAML for numbers, booleans, lists and mappings
+func ParseValue(key, text string) (interface{}, error) {
+	typ, err := KeyType(key)
+	if err != nil {
+		return nil, err
+	}
+	if typ.Kind() == reflect.String {
+		return text, nil
+	}
+
+	value := reflect.New(typ)
+	if err := yaml.Unmarshal([]byte(text), value.Interface()); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return value.Elem().Interface(), nil
+}
+
+// keyString formats key parts back into a dotted path
+func keyString(parts []keyPart) string {
+	key := ""
+	for _, part := range parts {
+		key = joinKey(key, part.name)
+		if part.index >= 0 {
+			key += "[" + strconv.Itoa(part.index) + "]"
+		}
+	}
+	return key
+}
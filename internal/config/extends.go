@@ -0,0 +1,262 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// extendsKey names the shared defaults a config is merged onto
+const extendsKey = "extends"
+
+// document is a config file merged onto the configs it extends
+type document struct {
+	root    *yaml.Node
+	sources map[*yaml.Node]string // file or URL each node was read from
+}
+
+// source returns where node was read from and its line, e.g. "org.yaml:3"
+func (d *document) source(node *yaml.Node) string {
+	return fmt.Sprintf("%s:%d", d.sources[node], node.Line)
+}
+
+// readConfig reads a config file and the configs it extends into a single
+// yaml.Node, with environment variables and !include tags expanded.
+// Expansion problems, such as undefined variables, are returned as problems
+// located in the file they come from.
+func readConfig(path string) (*document, problems, error) {
+	doc := &document{sources: map[*yaml.Node]string{}}
+	var ps problems
+
+	root, err := doc.read(path, path, map[string]bool{}, &ps)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc.root = root
+
+	// Problems in the main file are reported without its name
+	for i := range ps {
+		if ps[i].File == path {
+			ps[i].File = ""
+		}
+	}
+	return doc, ps, nil
+}
+
+// read parses the config at location, a path or URL, and merges it onto
+// the config it extends. seen guards against extends cycles.
+func (d *document) read(location, mainPath string, seen map[string]bool, ps *problems) (*yaml.Node, error) {
+	if seen[location] {
+		return nil, fmt.Errorf("extends cycle: %s is extended twice", location)
+	}
+	seen[location] = true
+
+	data, dir, err := fetchConfig(location)
+	if err != nil {
+		if location == mainPath {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		return nil, fmt.Errorf("failed to read extended config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", location, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("config file %s is empty", location)
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: config must be a mapping", location)
+	}
+
+	// Older config formats are upgraded in memory, tobrew config migrate
+	// rewrites the file
+	var fileProblems problems
+	from, changes, err := migrate(root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+	// Extended configs belong to someone else, migrating them is up to them
+	if len(changes) > 0 && location == mainPath {
+		fileProblems.warnf(schemaVersionKey, "config uses schema version %d, run tobrew config migrate to upgrade it to %d", from, SchemaVersion)
+	}
+	expand(root, dir, &fileProblems)
+	for i := range fileProblems {
+		fileProblems[i].File = location
+	}
+	*ps = append(*ps, fileProblems...)
+	d.record(root, location)
+
+	// Merge onto the extended config, if any
+	extends := removeKey(root, extendsKey)
+	if extends == nil || extends.Value == "" {
+		return root, nil
+	}
+	baseLocation, err := resolveLocation(location, extends.Value)
+	if err != nil {
+		return nil, err
+	}
+	base, err := d.read(baseLocation, mainPath, seen, ps)
+	if err != nil {
+		return nil, err
+	}
+	mergeNode(base, root)
+	return base, nil
+}
+
+// record remembers location as the source of node and its descendants
+func (d *document) record(node *yaml.Node, location string) {
+	d.sources[node] = location
+	for _, child := range node.Content {
+		d.record(child, location)
+	}
+}
+
+// fetchConfig returns the contents of a config path or URL and the directory
+// relative paths inside it refer to, "" for URLs
+func fetchConfig(location string) ([]byte, string, error) {
+	if !isURL(location) {
+		data, err := os.ReadFile(location)
+		return data, filepath.Dir(location), err
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to %s: extended configs must be fetched over https", req.URL)
+			}
+			return nil
+		},
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s: HTTP %d", location, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, "", err
+}
+
+// resolveLocation resolves ref, the extends value of the config at from,
+// to a path or URL
+func resolveLocation(from, ref string) (string, error) {
+	if strings.HasPrefix(ref, "http://") {
+		// A config fetched in the clear could be replaced on the way, and it
+		// sets commands tobrew runs
+		return "", fmt.Errorf("invalid extends %q: use an https:// URL", ref)
+	}
+	if isURL(ref) {
+		return ref, nil
+	}
+	if isURL(from) {
+		base, err := url.Parse(from)
+		if err != nil {
+			return "", err
+		}
+		rel, err := url.Parse(ref)
+		if err != nil {
+			return "", fmt.Errorf("invalid extends %q: %w", ref, err)
+		}
+		return base.ResolveReference(rel).String(), nil
+	}
+	if filepath.IsAbs(ref) {
+		return ref, nil
+	}
+	return filepath.Join(filepath.Dir(from), ref), nil
+}
+
+// isURL reports whether location is an https URL rather than a path
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "https://")
+}
+
+// mergeNode deep-merges the mapping src onto the mapping dst. Values in src
+// win; mappings present in both are merged key by key, while lists and
+// scalars are replaced as a whole.
+func mergeNode(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+
+		j := mappingIndex(dst, key.Value)
+		switch {
+		case j < 0:
+			dst.Content = append(dst.Content, key, value)
+		case dst.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNode(dst.Content[j+1], value)
+		default:
+			dst.Content[j+1] = value
+		}
+	}
+}
+
+// mappingIndex returns the index of key in a mapping node's content, or -1
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeKey deletes key from a mapping node and returns its value, or nil
+func removeKey(node *yaml.Node, key string) *yaml.Node {
+	i := mappingIndex(node, key)
+	if i < 0 {
+		return nil
+	}
+	value := node.Content[i+1]
+	node.Content = append(node.Content[:i], node.Content[i+2:]...)
+	return value
+}
+
+// Resolved returns the effective config at path, merged onto the configs it
+// extends and with variables and includes expanded, as YAML. Each value is
+// annotated with the file and line it came from.
+func Resolved(path string) ([]byte, error) {
+	if path == "" {
+		path = "tobrew.yaml"
+	}
+
+	doc, ps, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := ps.err(); err != nil {
+		return nil, err
+	}
+
+	doc.annotate(doc.root)
+	return yaml.Marshal(doc.root)
+}
+
+// annotate replaces the comments of node's values with their sources
+func (d *document) annotate(node *yaml.Node) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			// Keys
+			child.HeadComment, child.LineComment, child.FootComment = "", "", ""
+			continue
+		}
+		d.annotate(child)
+	}
+	// Nodes added in memory, such as by migrations, have no line to point at
+	if node.Kind == yaml.ScalarNode && node.Line > 0 {
+		node.LineComment = "# " + d.source(node)
+	}
+}
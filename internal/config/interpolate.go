@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeTag replaces a value with the contents of a file, e.g.
+// install: !include packaging/install.rb
+const includeTag = "!include"
+
+// envVarRe matches ${VAR}, ${VAR:-default} and the $${...} escape
+var envVarRe = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expand replaces ${VAR} references and !include tags in string values.
+// dir is "" for remote configs, whose values are taken literally: a shared
+// file elsewhere must not read the environment, which may hold secrets.
+func expand(node *yaml.Node, dir string, ps *problems) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		// Keys are never expanded
+		for i := 1; i < len(node.Content); i += 2 {
+			key, value := node.Content[i-1], node.Content[i]
+			expand(value, dir, ps)
+
+			// Anchor *_file paths to this file, it may be extended by a config elsewhere
+			if strings.HasSuffix(key.Value, "_file") && value.Kind == yaml.ScalarNode && value.Value != "" {
+				if dir == "" {
+					ps.at(value, "%s is not supported in remote configs", key.Value)
+				} else if !filepath.IsAbs(value.Value) {
+					value.Value = filepath.Join(dir, value.Value)
+				}
+			}
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			expand(child, dir, ps)
+		}
+	case yaml.ScalarNode:
+		if node.Tag == includeTag {
+			if dir == "" {
+				ps.at(node, "%s is not supported in remote configs", includeTag)
+				return
+			}
+			data, err := os.ReadFile(filepath.Join(dir, node.Value))
+			if err != nil {
+				ps.at(node, "%s %s: %v", includeTag, node.Value, err)
+				return
+			}
+			// Included files are taken literally, scripts may contain ${...}.
+			// A literal block is a string without an explicit tag.
+			node.Tag = ""
+			node.Style = yaml.LiteralStyle
+			node.Value = string(data)
+			return
+		}
+		if node.ShortTag() == "!!str" && dir != "" {
+			node.Value = expandEnv(node, ps)
+		}
+	}
+}
+
+// expandEnv returns the value of a scalar with ${VAR} and ${VAR:-default}
+// replaced. $${VAR} is kept as a literal ${VAR}.
+func expandEnv(node *yaml.Node, ps *problems) string {
+	return envVarRe.ReplaceAllStringFunc(node.Value, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+
+		m := envVarRe.FindStringSubmatch(ref)
+		value, ok := os.LookupEnv(m[1])
+		switch {
+		case value != "":
+			return value
+		case m[2] != "":
+			// Like the shell, empty variables fall back to the default too
+			return m[3]
+		case !ok:
+			ps.at(node, "environment variable %s is not set", m[1])
+		}
+		return ""
+	})
+}
+
+// at records an error located at node
+func (ps *problems) at(node *yaml.Node, format string, a ...interface{}) {
+	*ps = append(*ps, Problem{Message: fmt.Sprintf(format, a...), Line: node.Line, Column: node.Column})
+}
+
+// readFiles loads the formula scripts referenced by *_file keys. Relative
+// paths were anchored to their config file by expand.
+func (f *FormulaConfig) readFiles(key string, ps *problems) {
+	for _, script := range []struct {
+		name  string
+		file  string
+		value *string
+	}{
+		{"install", f.InstallFile, &f.Install},
+		{"test", f.TestFile, &f.Test},
+		{"caveats", f.CaveatsFile, &f.Caveats},
+	} {
+		if script.file == "" {
+			continue
+		}
+		fileKey := key + "." + script.name + "_file"
+		if *script.value != "" {
+			ps.errorf(fileKey, "%s.%s and %s are mutually exclusive", key, script.name, fileKey)
+			continue
+		}
+		data, err := os.ReadFile(script.file)
+		if err != nil {
+			ps.errorf(fileKey, "%s: %v", fileKey, err)
+			continue
+		}
+		*script.value = string(data)
+	}
+}
+
+// readFiles loads every file referenced by the config
+func (c *Config) readFiles() problems {
+	var ps problems
+	c.Formula.readFiles("formula", &ps)
+	for i := range c.Projects {
+		c.Projects[i].Formula.readFiles(fmt.Sprintf("projects[%d].formula", i), &ps)
+	}
+	return ps
+}
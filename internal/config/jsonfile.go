@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encodeJSON encodes a node read from the JSON file original back to JSON,
+// in the node's key order and with the file's indentation
+func encodeJSON(node *yaml.Node, original []byte) []byte {
+	var b strings.Builder
+	writeJSON(&b, node, strings.Repeat(" ", indentOf(original)), "")
+	b.WriteString("\n")
+	return []byte(b.String())
+}
+
+func writeJSON(b *strings.Builder, node *yaml.Node, indent, prefix string) {
+	inner := prefix + indent
+	switch node.Kind {
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, _ := json.Marshal(node.Content[i].Value)
+			b.WriteString(inner + string(key) + ": ")
+			writeJSON(b, node.Content[i+1], indent, inner)
+			if i+2 < len(node.Content) {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(prefix + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, item := range node.Content {
+			b.WriteString(inner)
+			writeJSON(b, item, indent, inner)
+			if i+1 < len(node.Content) {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(prefix + "]")
+	case yaml.AliasNode:
+		writeJSON(b, node.Alias, indent, prefix)
+	default:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			b.WriteString(node.Value)
+		case "!!null":
+			b.WriteString("null")
+		default:
+			value, _ := json.Marshal(node.Value)
+			b.Write(value)
+		}
+	}
+}
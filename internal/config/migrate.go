@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the version of the config file format this tobrew
+// writes. Files without schema_version are version 1.
+const SchemaVersion = 1
+
+const schemaVersionKey = "schema_version"
+
+// migration upgrades a config from one schema version to the next and
+// describes each change it made
+type migration func(root *yaml.Node) []string
+
+// migrations[i] upgrades schema version i+1 to i+2
+var migrations = []migration{}
+
+// schemaVersion returns the schema version of a config, 1 if unset
+func schemaVersion(root *yaml.Node) (int, error) {
+	node := mappingValue(root, schemaVersionKey)
+	if node == nil {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(node.Value)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("line %d: schema_version must be a positive number, got %q", node.Line, node.Value)
+	}
+	if v > SchemaVersion {
+		return 0, fmt.Errorf("line %d: schema_version %d is newer than this tobrew supports (%d), run tobrew self-update", node.Line, v, SchemaVersion)
+	}
+	return v, nil
+}
+
+// migrate upgrades a config to the current schema version in place and
+// returns the schema version it had and the changes made
+func migrate(root *yaml.Node) (int, []string, error) {
+	from, err := schemaVersion(root)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var changes []string
+	for v := from; v < SchemaVersion; v++ {
+		changes = append(changes, migrations[v-1](root)...)
+	}
+	if from < SchemaVersion {
+		setScalar(root, schemaVersionKey, strconv.Itoa(SchemaVersion), "!!int")
+		keepModeline(root)
+	}
+	return from, changes, nil
+}
+
+// Migrate upgrades the config file at path to the current schema version,
+// keeping its comments and key order. It returns the file's contents before
+// and after and the changes made; the file itself is not written.
+func Migrate(path string) (before, after []byte, changes []string, err error) {
+	if path == "" {
+		path = "tobrew.yaml"
+	}
+
+	f, err := OpenFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if f.format == "toml" {
+		return nil, nil, nil, fmt.Errorf("%s: config migrate supports YAML and JSON files", path)
+	}
+	before = f.data
+
+	from, changes, err := migrate(f.root())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if from == SchemaVersion {
+		return before, before, nil, nil
+	}
+
+	after, err = f.Bytes()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return before, after, changes, nil
+}
+
+// setScalar sets key of a mapping node to a scalar value, adding the key
+// at the top of the mapping if it is missing
+func setScalar(node *yaml.Node, key, value, tag string) {
+	if v := mappingValue(node, key); v != nil {
+		v.Kind, v.Tag, v.Value, v.Content = yaml.ScalarNode, tag, value, nil
+		return
+	}
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	node.Content = append([]*yaml.Node{keyNode, valueNode}, node.Content...)
+}
+
+// keepModeline moves the yaml-language-server modeline tobrew init writes
+// from the second key of a mapping to the first, so it stays on top
+func keepModeline(node *yaml.Node) {
+	if len(node.Content) < 4 {
+		return
+	}
+	first, second := node.Content[0], node.Content[2]
+	var modeline, rest []string
+	for _, line := range strings.Split(second.HeadComment, "\n") {
+		if strings.HasPrefix(line, "# yaml-language-server:") {
+			modeline = append(modeline, line)
+		} else if line != "" {
+			rest = append(rest, line)
+		}
+	}
+	if len(modeline) > 0 {
+		first.HeadComment = strings.Join(modeline, "\n")
+		second.HeadComment = strings.Join(rest, "\n")
+	}
+}
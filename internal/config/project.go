@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/version"
+)
+
+// ProjectConfig describes one formula released from a repository with
+// several tools. Empty fields inherit the top-level values.
+type ProjectConfig struct {
+	Name        string        `yaml:"name" json:"name" toml:"name"`
+	Language    string        `yaml:"language,omitempty" json:"language,omitempty" toml:"language,omitempty"`
+	Description string        `yaml:"description,omitempty" json:"description,omitempty" toml:"description,omitempty"`
+	Homepage    string        `yaml:"homepage,omitempty" json:"homepage,omitempty" toml:"homepage,omitempty"`
+	License     string        `yaml:"license,omitempty" json:"license,omitempty" toml:"license,omitempty"`
+	Build       BuildConfig   `yaml:"build,omitempty" json:"build,omitempty" toml:"build,omitempty"`
+	Formula     FormulaConfig `yaml:"formula,omitempty" json:"formula,omitempty" toml:"formula,omitempty"`
+	Rust        RustConfig    `yaml:"rust,omitempty" json:"rust,omitempty" toml:"rust,omitempty"`                   // manifest default: rust.manifest, then <name>/Cargo.toml
+	TagPrefix   string        `yaml:"tag_prefix,omitempty" json:"tag_prefix,omitempty" toml:"tag_prefix,omitempty"` // default: "<name>/", giving tags like foo/v1.2.3
+	TagFormat   string        `yaml:"tag_format,omitempty" json:"tag_format,omitempty" toml:"tag_format,omitempty"` // replaces the default prefix and version.tag_format
+}
+
+// HasProjects reports whether the config describes several projects
+func (c *Config) HasProjects() bool {
+	return len(c.Projects) > 0
+}
+
+// ProjectNames returns the names of the configured projects
+func (c *Config) ProjectNames() []string {
+	names := make([]string, 0, len(c.Projects))
+	for _, p := range c.Projects {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// Project returns the effective config of a single project: the top-level
+// config with the project's fields applied. With no projects configured
+// name must be empty and the config itself is returned.
+func (c *Config) Project(name string) (*Config, error) {
+	if !c.HasProjects() {
+		if name != "" {
+			return nil, fmt.Errorf("no projects configured, cannot release project %q", name)
+		}
+		return c, nil
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("project name required, choose one of: %s", strings.Join(c.ProjectNames(), ", "))
+	}
+
+	for _, p := range c.Projects {
+		if p.Name != name {
+			continue
+		}
+
+		resolved := *c
+		resolved.Projects = nil
+		resolved.Name = p.Name
+		resolved.TagPrefix = p.tagPrefix()
+		if p.TagFormat != "" {
+			resolved.Version.TagFormat = p.TagFormat
+		}
+		if p.Language != "" {
+			resolved.Language = p.Language
+		}
+		if p.Description != "" {
+			resolved.Description = p.Description
+		}
+		if p.Homepage != "" {
+			resolved.Homepage = p.Homepage
+		}
+		if p.License != "" {
+			resolved.License = p.License
+		}
+		if p.Build.Command != "" {
+			resolved.Build.Command = p.Build.Command
+		}
+		if p.Formula.Install != "" {
+			resolved.Formula.Install = p.Formula.Install
+		}
+		if p.Formula.Test != "" {
+			resolved.Formula.Test = p.Formula.Test
+		}
+		if p.Formula.Caveats != "" {
+			resolved.Formula.Caveats = p.Formula.Caveats
+		}
+		if len(p.Formula.Dependencies) > 0 {
+			resolved.Formula.Dependencies = p.Formula.Dependencies
+		}
+		if err := resolved.projectRust(p); err != nil {
+			return nil, err
+		}
+		return &resolved, nil
+	}
+
+	return nil, fmt.Errorf("unknown project %q, choose one of: %s", name, strings.Join(c.ProjectNames(), ", "))
+}
+
+// tagPrefix returns the tag prefix of a project. Projects with their own
+// tag_format don't get the default "<name>/" prefix.
+func (p ProjectConfig) tagPrefix() string {
+	if p.TagPrefix == "" && p.TagFormat == "" {
+		return p.Name + "/"
+	}
+	return p.TagPrefix
+}
+
+// validateProjects checks project names and tag formats are set and unique
+func (c *Config) validateProjects(ps *problems) {
+	names := map[string]bool{}
+	formats := map[string]string{}
+
+	for i, p := range c.Projects {
+		key := fmt.Sprintf("projects[%d]", i)
+		if p.Name == "" {
+			ps.errorf(key+".name", "%s.name is required", key)
+			continue
+		}
+		if names[p.Name] {
+			ps.errorf(key+".name", "%s.name %q is used more than once", key, p.Name)
+			continue
+		}
+		names[p.Name] = true
+		validateName(ps, key+".name", p.Name)
+		if p.Homepage != "" {
+			validateHomepage(ps, key+".homepage", p.Homepage)
+		}
+		if p.License != "" {
+			validateLicense(ps, key+".license", p.License)
+		}
+		validateDependencies(ps, key+".formula.dependencies", p.Formula.Dependencies)
+		validateTargets(ps, key+".rust.targets", p.Rust.Targets)
+
+		resolved, err := c.Project(p.Name)
+		if err != nil {
+			ps.errorf(key, "%v", err)
+			continue
+		}
+		format := resolved.tagFormat()
+		if _, err := version.ParseTagFormat(format); err != nil {
+			ps.errorf(key+".tag_format", "%s: %v", key, err)
+			continue
+		}
+		if other, ok := formats[format]; ok {
+			ps.errorf(key, "projects %q and %q share tag format %q", other, p.Name, format)
+		}
+		formats[format] = p.Name
+	}
+}
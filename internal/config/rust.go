@@ -0,0 +1,179 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/cargo"
+	"github.com/yejune/tobrew/internal/forge"
+)
+
+// Platform is where a binary archive runs, in the terms of the formula's
+// on_macos/on_linux and on_arm/on_intel blocks
+type Platform struct {
+	OS   string // macos or linux
+	Arch string // arm or intel
+}
+
+// TargetPlatform returns the platform of a Rust target triple, e.g.
+// macos/arm for aarch64-apple-darwin, false for targets Homebrew doesn't run on
+func TargetPlatform(target string) (Platform, bool) {
+	var p Platform
+	switch {
+	case strings.Contains(target, "-apple-darwin"):
+		p.OS = "macos"
+	case strings.Contains(target, "-linux-"):
+		p.OS = "linux"
+	default:
+		return p, false
+	}
+	switch arch, _, _ := strings.Cut(target, "-"); arch {
+	case "aarch64", "arm64":
+		p.Arch = "arm"
+	case "x86_64":
+		p.Arch = "intel"
+	default:
+		return p, false
+	}
+	return p, true
+}
+
+// CargoManifest returns the path of the project's Cargo.toml
+func (c *Config) CargoManifest() string {
+	if c.Rust.Manifest != "" {
+		return c.Rust.Manifest
+	}
+	return "Cargo.toml"
+}
+
+// RustBinary returns the name of the binary packed into the archives of
+// rust.targets
+func (c *Config) RustBinary() string {
+	if c.Rust.Binary != "" {
+		return c.Rust.Binary
+	}
+	return c.Name
+}
+
+// readCargo fills name, description, license and homepage of rust projects
+// from Cargo.toml where the config leaves them empty
+func (c *Config) readCargo() problems {
+	var ps problems
+	if c.Language != "rust" {
+		return ps
+	}
+	m, err := cargo.ReadManifest(c.CargoManifest())
+	if errors.Is(err, fs.ErrNotExist) && c.Rust.Manifest == "" {
+		return ps
+	}
+	if err != nil {
+		ps.errorf("rust.manifest", "rust.manifest: %v", err)
+		return ps
+	}
+	if c.Name == "" && len(c.Projects) == 0 {
+		c.Name = m.Name
+	}
+	c.applyCargo(m)
+	return ps
+}
+
+// applyCargo sets the formula metadata the config leaves empty from a
+// Cargo.toml
+func (c *Config) applyCargo(m *cargo.Manifest) {
+	c.Description = firstNonEmpty(c.Description, m.Description)
+	c.License = firstNonEmpty(c.License, m.License)
+	c.Homepage = firstNonEmpty(c.Homepage, m.Homepage, m.Repository)
+}
+
+// projectRust applies the rust settings of a project to its resolved
+// config. The project's Cargo.toml comes before the inherited top-level
+// metadata, but after the project's own.
+func (c *Config) projectRust(p ProjectConfig) error {
+	if p.Rust.Manifest != "" {
+		c.Rust.Manifest = p.Rust.Manifest
+	} else if c.Rust.Manifest == "" {
+		c.Rust.Manifest = path.Join(p.Name, "Cargo.toml")
+	}
+	if len(p.Rust.Targets) > 0 {
+		c.Rust.Targets = p.Rust.Targets
+	}
+	if p.Rust.Binary != "" {
+		c.Rust.Binary = p.Rust.Binary
+	}
+	if c.Language != "rust" {
+		return nil
+	}
+
+	m, err := cargo.ReadManifest(c.CargoManifest())
+	if errors.Is(err, fs.ErrNotExist) && p.Rust.Manifest == "" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("project %s: %w", p.Name, err)
+	}
+	c.Description = firstNonEmpty(p.Description, m.Description, c.Description)
+	c.License = firstNonEmpty(p.License, m.License, c.License)
+	c.Homepage = firstNonEmpty(p.Homepage, m.Homepage, m.Repository, c.Homepage)
+	return nil
+}
+
+// validateRust checks the rust sections and the settings they depend on
+func (c *Config) validateRust(ps *problems) {
+	rust := c.Language == "rust"
+	targets := len(c.Rust.Targets) > 0
+	for _, p := range c.Projects {
+		rust = rust || p.Language == "rust"
+		targets = targets || len(p.Rust.Targets) > 0
+	}
+	if !rust && (c.Rust.Manifest != "" || targets || c.Rust.Binary != "") {
+		ps.warnf("rust", "rust settings are ignored, language is not rust")
+	}
+	if c.VersionFromCargo() && !rust {
+		ps.errorf("version.source", "version.source: cargo requires language: rust")
+	}
+
+	validateTargets(ps, "rust.targets", c.Rust.Targets)
+	if !targets {
+		return
+	}
+	if c.Forge.Type != "" && c.Forge.Type != forge.TypeGitHub {
+		ps.errorf("rust.targets", "rust.targets needs a GitHub repository, the archives are published as GitHub release assets")
+	}
+	if c.GitHub.Private {
+		ps.errorf("rust.targets", "rust.targets is not supported with github.private, brew can't download release assets of private repositories")
+	}
+	if len(c.Rust.Targets) > 0 && strings.Contains(c.Formula.Install, "cargo") {
+		ps.warnf("formula.install", "formula.install builds with cargo, but rust.targets publishes binaries: use bin.install %q", c.RustBinary())
+	}
+}
+
+// validateTargets checks target triples run on a Homebrew platform, one
+// target per platform
+func validateTargets(ps *problems, key string, targets []string) {
+	platforms := map[Platform]string{}
+	for i, target := range targets {
+		targetKey := fmt.Sprintf("%s[%d]", key, i)
+		p, ok := TargetPlatform(target)
+		if !ok {
+			ps.errorf(targetKey, "%s %q is not a macOS or Linux target for arm64 or x86_64", targetKey, target)
+			continue
+		}
+		if other, ok := platforms[p]; ok {
+			ps.errorf(targetKey, "%s: %q and %q both build for %s/%s", key, other, target, p.OS, p.Arch)
+		}
+		platforms[p] = target
+	}
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// SchemaURL is where the JSON Schema of the config file is published
+const SchemaURL = "https://raw.githubusercontent.com/yejune/tobrew/main/schema/tobrew.schema.json"
+
+// jsonSchema is the subset of JSON Schema (draft-07) used to describe the config
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+}
+
+// schemaHint documents a config key in the schema. Keys are dotted paths
+// without list indexes, e.g. "taps.mode".
+type schemaHint struct {
+	description string
+	enum        []string
+	pattern     string
+	required    []string
+}
+
+var schemaHints = map[string]schemaHint{
+	"$schema":        {description: "JSON Schema of this file, for editors. Ignored by tobrew."},
+	"schema_version": {description: "Version of the config file format, 1 if unset. tobrew config migrate upgrades older files."},
+	"extends":        {description: "File or https:// URL of shared defaults. This config is deep-merged onto them and wins."},
+	"name":           {description: "Formula name. Required unless projects are configured.", pattern: formulaNameRe.String()},
+	"language":       {description: "Project language, optionally with a version such as php@8.4.", pattern: `^(go|rust|python|node|php|binary)(@[0-9.]+)?$`},
+	"description":    {description: "One-line formula description."},
+	"homepage":       {description: "Project homepage, an http(s) URL."},
+	"license":        {description: "SPDX license expression, e.g. MIT or Apache-2.0 OR MIT."},
+
+	"forge":             {description: "Git hosting service of the project and its taps."},
+	"forge.type":        {description: "Forge type.", enum: []string{"github", "gitlab", "gitea", "generic"}},
+	"forge.url":         {description: "Base URL of a self-hosted instance."},
+	"forge.tarball_url": {description: "Tarball URL template for generic forges, e.g. {{.URL}}/{{.Owner}}/{{.Repo}}/archive/{{.Tag}}.tar.gz."},
+
+	"github":              {description: "Repository coordinates, on whichever forge is configured. user and repo are required, but may come from extends."},
+	"github.user":         {description: "Owner of the project repository."},
+	"github.repo":         {description: "Name of the project repository."},
+	"github.tap_repo":     {description: "Tap repository, e.g. homebrew-tap. Required unless taps are configured."},
+	"github.tap_url":      {description: "Explicit clone URL of the tap (https, ssh or file://)."},
+	"github.tap_protocol": {description: "Protocol used to clone the tap.", enum: []string{ProtocolHTTPS, ProtocolSSH}},
+	"github.token_env":    {description: "Environment variable holding the forge token."},
+	"github.private":      {description: "Download sources through the authenticated forge API."},
+
+	"build.command":   {description: "Command run before tagging a release."},
+	"formula.install": {description: "Ruby body of the formula's install method."},
+	"formula.test":    {description: "Ruby body of the formula's test block."},
+	"formula.caveats": {description: "Text shown after installation."},
+
+	"formula.install_file": {description: "File holding formula.install, relative to the config file."},
+	"formula.test_file":    {description: "File holding formula.test, relative to the config file."},
+	"formula.caveats_file": {description: "File holding formula.caveats, relative to the config file."},
+
+	"formula.dependencies":      {description: "Formula dependencies besides the language's own.", required: []string{"name"}},
+	"formula.dependencies.name": {description: "Homebrew formula depended on, e.g. openssl@3."},
+	"formula.dependencies.type": {description: "Dependency type, default: runtime.", enum: DependencyTypes},
+
+	"taps":           {description: "Tap repositories the formula is published to."},
+	"taps.owner":     {description: "Tap owner, default: github.user."},
+	"taps.repo":      {description: "Tap repository, e.g. homebrew-tap."},
+	"taps.branch":    {description: "Tap branch, default: main."},
+	"taps.directory": {description: "Formula directory inside the tap, e.g. Formula."},
+	"taps.mode":      {description: "Commit to the branch directly, or push a branch for review.", enum: []string{TapModePush, TapModeBranch}},
+	"taps.url":       {description: "Explicit clone URL (https, ssh or file://)."},
+	"taps.protocol":  {description: "Protocol used to clone the tap, default: github.tap_protocol.", enum: []string{ProtocolHTTPS, ProtocolSSH}},
+
+	"rust":          {description: "Settings of rust projects. Empty name, description, license and homepage are read from Cargo.toml."},
+	"rust.manifest": {description: "Cargo.toml of the crate, default: Cargo.toml."},
+	"rust.targets":  {description: "Target triples to publish binary archives for, e.g. aarch64-apple-darwin. The formula installs these instead of building from source."},
+	"rust.binary":   {description: "Binary packed into the archives, default: name."},
+
+	"version.tag_format":    {description: "Release tag template, default: v{{.Version}}."},
+	"version.source":        {description: "Where the current version comes from.", enum: []string{VersionSourceLock, VersionSourceGit, VersionSourceCargo}},
+	"version.lock":          {description: "With source git, whether tobrew.lock is written.", enum: []string{LockRecord, LockNone}},
+	"version.behind_remote": {description: "What to do when tobrew.lock is behind the tags on origin.", enum: []string{BehindRemoteSync, BehindRemoteFail}},
+	"projects":              {description: "Formulas released from this repository, each overriding top-level settings."},
+	"projects.name":         {description: "Formula name of the project.", pattern: formulaNameRe.String()},
+}
+
+// Schema returns the JSON Schema of the config file, generated from Config
+func Schema() ([]byte, error) {
+	s := schemaFor(reflect.TypeOf(Config{}), "")
+	s.Schema = "http://json-schema.org/draft-07/schema#"
+	s.ID = SchemaURL
+	s.Title = "tobrew configuration"
+	s.Description = "Configuration of tobrew, the Homebrew tap release tool"
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaFor returns the schema of a config type found at key
+func schemaFor(typ reflect.Type, key string) *jsonSchema {
+	hint := schemaHints[key]
+	s := &jsonSchema{
+		Description: hint.description,
+		Enum:        hint.enum,
+		Pattern:     hint.pattern,
+		Required:    hint.required,
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		s.Type = "object"
+		s.AdditionalProperties = false
+		s.Properties = map[string]*jsonSchema{}
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			s.Properties[name] = schemaFor(f.Type, joinKey(key, name))
+		}
+	case reflect.Slice, reflect.Array:
+		s.Type = "array"
+		s.Items = schemaFor(typ.Elem(), key)
+		// The description belongs to the list, required keys to each item
+		s.Items.Description = ""
+		s.Required = nil
+	case reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = schemaFor(typ.Elem(), key)
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		s.Type = "integer"
+	default:
+		s.Type = "string"
+	}
+	return s
+}
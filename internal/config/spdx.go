@@ -0,0 +1,53 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// CommonLicenses are suggested when asking for a license
+var CommonLicenses = []string{"MIT", "Apache-2.0", "BSD-3-Clause", "BSD-2-Clause", "GPL-3.0-only", "LGPL-3.0-only", "MPL-2.0", "ISC", "Unlicense"}
+
+// spdxLicenses holds the SPDX identifiers of commonly used licenses. It is not
+// the full SPDX list, so unknown identifiers are reported as warnings only.
+var spdxLicenses = map[string]bool{}
+
+func init() {
+	for _, id := range []string{
+		"0BSD", "AFL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later", "Apache-1.1", "Apache-2.0",
+		"APSL-2.0", "Artistic-1.0", "Artistic-2.0", "BlueOak-1.0.0", "BSD-1-Clause",
+		"BSD-2-Clause", "BSD-2-Clause-Patent", "BSD-3-Clause", "BSD-3-Clause-Clear",
+		"BSD-4-Clause", "BSL-1.0", "BUSL-1.1", "CC-BY-4.0", "CC-BY-SA-4.0", "CC0-1.0",
+		"CDDL-1.0", "CDDL-1.1", "CECILL-2.1", "CPL-1.0", "ECL-2.0", "EPL-1.0", "EPL-2.0",
+		"EUPL-1.1", "EUPL-1.2", "GPL-2.0-only", "GPL-2.0-or-later", "GPL-3.0-only",
+		"GPL-3.0-or-later", "HPND", "ICU", "IJG", "ISC", "LGPL-2.0-only", "LGPL-2.0-or-later",
+		"LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0-only", "LGPL-3.0-or-later", "Libpng",
+		"MirOS", "MIT", "MIT-0", "MPL-1.1", "MPL-2.0", "MPL-2.0-no-copyleft-exception",
+		"MS-PL", "MS-RL", "MulanPSL-2.0", "NCSA", "OFL-1.1", "OpenSSL", "OSL-3.0", "PHP-3.01",
+		"PostgreSQL", "PSF-2.0", "Python-2.0", "Ruby", "Sleepycat", "SSPL-1.0", "Unicode-3.0",
+		"Unicode-DFS-2016", "Unlicense", "UPL-1.0", "Vim", "W3C", "WTFPL", "X11", "Zlib",
+		"ZPL-2.1",
+		// Deprecated forms that are still common
+		"AGPL-3.0", "GPL-2.0", "GPL-3.0", "LGPL-2.1", "LGPL-3.0",
+	} {
+		spdxLicenses[id] = true
+	}
+}
+
+// ValidLicense reports whether license is an SPDX license expression made of known identifiers
+func ValidLicense(license string) bool {
+	return strings.TrimSpace(license) != "" && len(unknownLicenses(license)) == 0
+}
+
+// SuggestLicenses returns the known SPDX identifiers containing input, ignoring case
+func SuggestLicenses(input string) []string {
+	input = strings.ToLower(strings.TrimSpace(input))
+	var matches []string
+	for id := range spdxLicenses {
+		if strings.Contains(strings.ToLower(id), input) {
+			matches = append(matches, id)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// setTOML sets a key of a TOML file by rewriting the line that holds it,
+// or adding one to its table. Tables are found by their [header] or
+// [[header]]; dotted keys, inline tables and values spanning several lines
+// are left for editing by hand.
+func (f *File) setTOML(key string, parts []keyPart, value interface{}) error {
+	last := parts[len(parts)-1]
+	if last.index >= 0 {
+		return fmt.Errorf("%s: list items can't be set in TOML files, set the whole list", key)
+	}
+	table, tableIndex := tomlTable(parts[:len(parts)-1])
+	if tableIndex == -2 {
+		return fmt.Errorf("%s: nested lists can't be set in TOML files, edit the file by hand", key)
+	}
+
+	formatted, err := tomlValue(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	line := last.name + " = " + formatted
+
+	start, end, ok := f.tomlTableLines(table, tableIndex)
+	if !ok {
+		if tableIndex > f.tomlTableCount(table) {
+			return fmt.Errorf("%s: there are only %d [[%s]] tables", key, f.tomlTableCount(table), table)
+		}
+		header := "[" + table + "]"
+		if tableIndex >= 0 {
+			header = "[[" + table + "]]"
+		}
+		f.appendTOML("", header, line)
+		return nil
+	}
+
+	for i := start; i < end; i++ {
+		name, rest, ok := tomlKeyLine(f.lines[i])
+		if !ok || name != last.name {
+			continue
+		}
+		if multiline(rest) {
+			return fmt.Errorf("%s spans several lines in %s, edit it by hand", key, f.path)
+		}
+		indent := f.lines[i][:len(f.lines[i])-len(strings.TrimLeft(f.lines[i], " \t"))]
+		f.lines[i] = indent + line + tomlComment(rest)
+		return nil
+	}
+
+	// A new key goes after the table's last line that isn't blank
+	at := end
+	for at > start && strings.TrimSpace(f.lines[at-1]) == "" {
+		at--
+	}
+	f.lines = append(f.lines[:at], append([]string{line}, f.lines[at:]...)...)
+	return nil
+}
+
+// tomlTable returns the table header of key parts, e.g. "github" or
+// "projects.formula", and the index of the [[array]] table they refer to,
+// -1 for plain tables and -2 for lists below the first level
+func tomlTable(parts []keyPart) (string, int) {
+	index := -1
+	names := make([]string, len(parts))
+	for i, part := range parts {
+		names[i] = part.name
+		if part.index >= 0 {
+			if i > 0 {
+				return "", -2
+			}
+			index = part.index
+		}
+	}
+	return strings.Join(names, "."), index
+}
+
+// tomlTableLines returns the range of lines holding the keys of a table,
+// the lines before the first header for the root table
+func (f *File) tomlTableLines(table string, index int) (int, int, bool) {
+	start, seen := -1, 0
+	if table == "" {
+		start = 0
+	}
+	for i, line := range f.lines {
+		header, array, ok := tomlHeader(line)
+		if !ok {
+			continue
+		}
+		if start >= 0 {
+			return start, i, true
+		}
+		if header == table && array == (index >= 0) {
+			if !array || seen == index {
+				start = i + 1
+			}
+			seen++
+		}
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+	return start, len(f.lines), true
+}
+
+// tomlTableCount returns the number of [[table]] headers of an array table
+func (f *File) tomlTableCount(table string) int {
+	count := 0
+	for _, line := range f.lines {
+		if header, array, ok := tomlHeader(line); ok && array && header == table {
+			count++
+		}
+	}
+	return count
+}
+
+// appendTOML adds lines at the end of the file, after a blank line
+func (f *File) appendTOML(lines ...string) {
+	for len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1]) == "" {
+		f.lines = f.lines[:len(f.lines)-1]
+	}
+	if len(f.lines) == 0 {
+		lines = lines[1:]
+	}
+	f.lines = append(append(f.lines, lines...), "")
+}
+
+// tomlHeader parses a table header line, [name] or [[name]]
+func tomlHeader(line string) (string, bool, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") {
+		return "", false, false
+	}
+	if i := strings.LastIndex(line, "]"); i >= 0 {
+		line = line[:i+1]
+	}
+	if strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]") {
+		return strings.TrimSpace(line[2 : len(line)-2]), true, true
+	}
+	return strings.TrimSpace(line[1 : len(line)-1]), false, true
+}
+
+// tomlKeyLine splits a key/value line into the key and the rest after "="
+func tomlKeyLine(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "[") {
+		return "", "", false
+	}
+	name, rest, ok := strings.Cut(trimmed, "=")
+	if !ok {
+		return "", "", false
+	}
+	return strings.Trim(strings.TrimSpace(name), `"'`), rest, true
+}
+
+// multiline reports whether a value continues on the next lines
+func multiline(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''") {
+		quote := rest[:3]
+		return !strings.Contains(rest[3:], quote)
+	}
+	if strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, "{") {
+		return strings.Count(rest, "[")+strings.Count(rest, "{") > strings.Count(rest, "]")+strings.Count(rest, "}")
+	}
+	return false
+}
+
+// tomlComment returns the comment after the value of a key line, with the
+// spaces before it
+func tomlComment(rest string) string {
+	var quote byte
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; {
+		case quote != 0 && c == '\\' && quote == '"':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			start := i
+			for start > 0 && (rest[start-1] == ' ' || rest[start-1] == '\t') {
+				start--
+			}
+			return rest[start:]
+		}
+	}
+	return ""
+}
+
+// tomlValue formats value as a TOML value for a single line. Strings are
+// basic "quoted" strings, like tobrew init writes them; JSON escapes are
+// valid in those.
+func tomlValue(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(s); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+	data, err := toml.Marshal(map[string]interface{}{"v": value})
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSuffix(string(data), "\n")
+	if !strings.HasPrefix(line, "v = ") || strings.Contains(line, "\n") {
+		return "", fmt.Errorf("tables can't be set in TOML files, edit the file by hand")
+	}
+	return strings.TrimPrefix(line, "v = "), nil
+}
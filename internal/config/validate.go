@@ -0,0 +1,359 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/forge"
+	"github.com/yejune/tobrew/internal/version"
+	"gopkg.in/yaml.v3"
+)
+
+// Placeholders written by tobrew init, which must be replaced before releasing
+const (
+	PlaceholderUser        = "USERNAME"
+	PlaceholderDescription = "Description of your project"
+)
+
+// formulaNameRe matches the file names Homebrew accepts for formulas
+var formulaNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._+@-]*$`)
+
+// Problem is an invalid or suspicious setting in a config file
+type Problem struct {
+	Key     string // dotted key path, e.g. "taps[0].mode"
+	Message string
+	Warning bool   // the config works, but probably not as intended
+	File    string // extended config the problem is in, "" for the config itself
+	Line    int    // position in the file, 0 if unknown
+	Column  int
+}
+
+// String formats the problem like a compiler diagnostic, without position
+func (p Problem) String() string {
+	if p.Warning {
+		return "warning: " + p.Message
+	}
+	return "error: " + p.Message
+}
+
+// problems collects the problems found while validating a config
+type problems []Problem
+
+func (ps *problems) errorf(key string, format string, a ...interface{}) {
+	*ps = append(*ps, Problem{Key: key, Message: fmt.Sprintf(format, a...)})
+}
+
+func (ps *problems) warnf(key string, format string, a ...interface{}) {
+	*ps = append(*ps, Problem{Key: key, Message: fmt.Sprintf(format, a...), Warning: true})
+}
+
+// Validate checks a config file and returns every problem found, located in
+// the file. The error is only set if the file can't be read or parsed at all.
+func Validate(path string) ([]Problem, error) {
+	if path == "" {
+		path = "tobrew.yaml"
+	}
+
+	doc, ps, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := doc.root.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	ps = append(ps, config.readFiles()...)
+	ps = append(ps, config.readCargo()...)
+	ps = append(ps, config.problems()...)
+	unknownKeys(&ps, doc.root, reflect.TypeOf(config), "")
+
+	for i := range ps {
+		if ps[i].Line > 0 {
+			continue
+		}
+		n := locate(doc.root, ps[i].Key)
+		source := doc.sources[n]
+		if n == doc.root && source != path {
+			// A missing top-level key, the extended config's root says nothing
+			continue
+		}
+		ps[i].Line, ps[i].Column = n.Line, n.Column
+		if source != path {
+			ps[i].File = source
+		}
+	}
+	sort.SliceStable(ps, func(i, j int) bool { return ps[i].Line < ps[j].Line })
+
+	return ps, nil
+}
+
+// err returns the first error, ignoring warnings
+func (ps problems) err() error {
+	for _, p := range ps {
+		if p.Warning {
+			continue
+		}
+		if p.File != "" {
+			return fmt.Errorf("%s:%d: %s", p.File, p.Line, p.Message)
+		}
+		if p.Line > 0 {
+			return fmt.Errorf("line %d: %s", p.Line, p.Message)
+		}
+		return fmt.Errorf("%s", p.Message)
+	}
+	return nil
+}
+
+// problems checks required fields and setting values
+func (c *Config) problems() problems {
+	var ps problems
+
+	// Required fields
+	if c.Name == "" && len(c.Projects) == 0 {
+		ps.errorf("name", "name is required")
+	}
+	if c.GitHub.User == "" {
+		ps.errorf("github.user", "github.user is required")
+	}
+	if c.GitHub.Repo == "" {
+		ps.errorf("github.repo", "github.repo is required")
+	}
+	if c.GitHub.TapRepo == "" && len(c.Taps) == 0 {
+		ps.errorf("github.tap_repo", "github.tap_repo or taps is required")
+	}
+	for i, tap := range c.Taps {
+		key := fmt.Sprintf("taps[%d]", i)
+		if tap.Repo == "" {
+			ps.errorf(key+".repo", "%s.repo is required", key)
+		}
+		if tap.Mode != "" && tap.Mode != TapModePush && tap.Mode != TapModeBranch {
+			ps.errorf(key+".mode", "%s.mode must be %q or %q, got %q", key, TapModePush, TapModeBranch, tap.Mode)
+		}
+		if !validProtocol(tap.Protocol) {
+			ps.errorf(key+".protocol", "%s.protocol must be %q or %q, got %q", key, ProtocolHTTPS, ProtocolSSH, tap.Protocol)
+		}
+		if strings.Contains(tap.Owner, PlaceholderUser) {
+			ps.errorf(key+".owner", "%s.owner is still the %s placeholder from tobrew init", key, PlaceholderUser)
+		}
+	}
+	if !validProtocol(c.GitHub.TapProtocol) {
+		ps.errorf("github.tap_protocol", "github.tap_protocol must be %q or %q, got %q", ProtocolHTTPS, ProtocolSSH, c.GitHub.TapProtocol)
+	}
+
+	// Leftovers from tobrew init
+	if strings.Contains(c.GitHub.User, PlaceholderUser) {
+		ps.errorf("github.user", "github.user is still the %s placeholder from tobrew init", PlaceholderUser)
+	}
+	if strings.Contains(c.Homepage, PlaceholderUser) {
+		ps.errorf("homepage", "homepage still contains the %s placeholder from tobrew init", PlaceholderUser)
+	}
+	if c.Description == PlaceholderDescription {
+		ps.errorf("description", "description is still the placeholder from tobrew init")
+	}
+
+	// Formula metadata
+	if c.Name != "" {
+		validateName(&ps, "name", c.Name)
+	}
+	if c.Homepage == "" {
+		ps.warnf("homepage", "homepage is empty, Homebrew requires one")
+	} else {
+		validateHomepage(&ps, "homepage", c.Homepage)
+	}
+	if c.License == "" {
+		ps.warnf("license", "license is empty")
+	} else {
+		validateLicense(&ps, "license", c.License)
+	}
+	validateDependencies(&ps, "formula.dependencies", c.Formula.Dependencies)
+
+	// Versioning
+	if _, err := version.ParseTagFormat(c.tagFormat()); err != nil {
+		ps.errorf("version.tag_format", "version.tag_format: %v", err)
+	}
+	if c.Version.Source != "" && c.Version.Source != VersionSourceLock && c.Version.Source != VersionSourceGit && c.Version.Source != VersionSourceCargo {
+		ps.errorf("version.source", "version.source must be %q, %q or %q, got %q", VersionSourceLock, VersionSourceGit, VersionSourceCargo, c.Version.Source)
+	}
+	if c.Version.Lock != "" && c.Version.Lock != LockRecord && c.Version.Lock != LockNone {
+		ps.errorf("version.lock", "version.lock must be %q or %q, got %q", LockRecord, LockNone, c.Version.Lock)
+	}
+	if c.Version.BehindRemote != "" && c.Version.BehindRemote != BehindRemoteSync && c.Version.BehindRemote != BehindRemoteFail {
+		ps.errorf("version.behind_remote", "version.behind_remote must be %q or %q, got %q", BehindRemoteSync, BehindRemoteFail, c.Version.BehindRemote)
+	}
+	if c.Version.Lock == LockNone && !c.VersionFromGit() {
+		ps.errorf("version.lock", "version.lock: none requires version.source: git")
+	}
+	c.validateProjects(&ps)
+	c.validateRust(&ps)
+
+	// Forge
+	f, err := forge.New(c.Forge.Type, c.Forge.URL, c.Forge.TarballURL)
+	if err != nil {
+		ps.errorf("forge", "%v", err)
+	} else if c.GitHub.Private && f.ArchiveAPIURL(c.GitHub.User, c.GitHub.Repo, "v0.0.0") == "" {
+		ps.errorf("github.private", "github.private is not supported for %s forges", f.Type())
+	}
+
+	return ps
+}
+
+// validateName checks name can be used as a Homebrew formula name
+func validateName(ps *problems, key, name string) {
+	if !ValidFormulaName(name) {
+		ps.errorf(key, "%s %q is not a valid formula name, use lowercase letters, digits and - _ . + @", key, name)
+	}
+}
+
+// ValidFormulaName reports whether name can be used as a Homebrew formula name
+func ValidFormulaName(name string) bool {
+	return formulaNameRe.MatchString(name)
+}
+
+// validateHomepage checks homepage is an absolute http(s) URL
+func validateHomepage(ps *problems, key, homepage string) {
+	u, err := url.Parse(homepage)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		ps.errorf(key, "%s %q is not an http(s) URL", key, homepage)
+	}
+}
+
+// validateLicense checks license is an SPDX license expression, e.g.
+// "MIT" or "Apache-2.0 OR MIT"
+func validateLicense(ps *problems, key, license string) {
+	for _, id := range unknownLicenses(license) {
+		ps.warnf(key, "%s %q is not a known SPDX license identifier (see https://spdx.org/licenses/)", key, id)
+	}
+}
+
+// validateDependencies checks formula dependencies have a name and a known type
+func validateDependencies(ps *problems, key string, deps []Dependency) {
+	for i, dep := range deps {
+		depKey := fmt.Sprintf("%s[%d]", key, i)
+		if dep.Name == "" {
+			ps.errorf(depKey+".name", "%s.name is required", depKey)
+		}
+		if dep.Type != "" && !slices.Contains(DependencyTypes, dep.Type) {
+			ps.errorf(depKey+".type", "%s.type must be one of %s, got %q", depKey, strings.Join(DependencyTypes, ", "), dep.Type)
+		}
+	}
+}
+
+// unknownLicenses returns the identifiers of a license expression that are
+// not known SPDX licenses. Exceptions after WITH are not checked.
+func unknownLicenses(license string) []string {
+	var unknown []string
+	fields := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(license))
+	for i, id := range fields {
+		switch id {
+		case "AND", "OR", "WITH":
+			continue
+		}
+		if i > 0 && fields[i-1] == "WITH" {
+			continue
+		}
+		if !spdxLicenses[strings.TrimSuffix(id, "+")] {
+			unknown = append(unknown, id)
+		}
+	}
+	return unknown
+}
+
+// unknownKeys reports mapping keys in node that have no field in typ
+func unknownKeys(ps *problems, node *yaml.Node, typ reflect.Type, prefix string) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch {
+	case node.Kind == yaml.MappingNode && typ.Kind() == reflect.Struct:
+		fields := yamlFields(typ)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				ps.warnf(joinKey(prefix, key.Value), "unknown key %q", joinKey(prefix, key.Value))
+				continue
+			}
+			unknownKeys(ps, value, field.Type, joinKey(prefix, key.Value))
+		}
+
+	case node.Kind == yaml.SequenceNode && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array):
+		for i, item := range node.Content {
+			unknownKeys(ps, item, typ.Elem(), prefix+"["+strconv.Itoa(i)+"]")
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of a struct type to its fields
+func yamlFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// joinKey appends a mapping key to a dotted key path
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// locate returns the node of a dotted key path such as "taps[0].mode". If
+// the key is missing, the node of its closest existing parent is returned.
+func locate(node *yaml.Node, key string) *yaml.Node {
+	if key == "" {
+		return node
+	}
+
+	for _, part := range strings.Split(key, ".") {
+		name, index := part, -1
+		if open := strings.Index(part, "["); open >= 0 && strings.HasSuffix(part, "]") {
+			name = part[:open]
+			index, _ = strconv.Atoi(part[open+1 : len(part)-1])
+		}
+
+		child := mappingValue(node, name)
+		if child == nil {
+			return node
+		}
+		node = child
+
+		if index >= 0 {
+			if node.Kind != yaml.SequenceNode || index >= len(node.Content) {
+				return node
+			}
+			node = node.Content[index]
+		}
+	}
+	return node
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	if i := mappingIndex(node, key); i >= 0 {
+		return node.Content[i+1]
+	}
+	return nil
+}
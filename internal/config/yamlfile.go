@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encodeYAML encodes a document read from original, keeping the layout
+// yaml.v3 doesn't: the indentation width and the blank lines between keys.
+// Comments and key order are kept by the nodes themselves.
+func encodeYAML(doc *yaml.Node, original []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indentOf(original))
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	// Find the keys that had a blank line before them, by path. The nodes
+	// still have the lines they were read from; added ones have none and
+	// are spaced like the key before them.
+	lines := strings.Split(string(original), "\n")
+	spaced := map[string]bool{}
+	previous := map[string]string{} // last key seen in each mapping
+	walkKeys(doc, "", func(key string, node *yaml.Node) {
+		parent := key[:max(strings.LastIndex(key, "."), 0)]
+		if above := keyStart(node) - 1; node.Line > 0 && above >= 1 && above <= len(lines) && strings.TrimSpace(lines[above-1]) == "" {
+			spaced[key] = true
+		} else if node.Line == 0 && previous[parent] != "" {
+			spaced[key] = spaced[previous[parent]]
+		}
+		previous[parent] = key
+	})
+
+	// and put blank lines back before the same keys
+	var encoded yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &encoded); err != nil {
+		return nil, fmt.Errorf("re-reading encoded config: %w", err)
+	}
+	out := strings.Split(buf.String(), "\n")
+	blankBefore := map[int]bool{}
+	walkKeys(&encoded, "", func(key string, node *yaml.Node) {
+		if spaced[key] {
+			blankBefore[keyStart(node)] = true
+		}
+	})
+
+	var result []string
+	for i, line := range out {
+		if blankBefore[i+1] && i > 0 && strings.TrimSpace(out[i-1]) != "" {
+			result = append(result, "")
+		}
+		result = append(result, line)
+	}
+	return []byte(strings.Join(result, "\n")), nil
+}
+
+// walkKeys calls fn with the dotted path and node of every mapping key
+// under node, e.g. "taps[0].repo"
+func walkKeys(node *yaml.Node, path string, fn func(key string, node *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkKeys(child, path, fn)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := joinKey(path, node.Content[i].Value)
+			fn(key, node.Content[i])
+			walkKeys(node.Content[i+1], key, fn)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			walkKeys(child, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
+
+// keyStart returns the first line of a key, including its head comment
+func keyStart(key *yaml.Node) int {
+	if key.HeadComment == "" {
+		return key.Line
+	}
+	return key.Line - strings.Count(key.HeadComment, "\n") - 1
+}
+
+// indentOf returns the indentation width a YAML file uses, 2 if unknown
+func indentOf(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if n := len(line) - len(trimmed); n > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return n
+		}
+	}
+	return 2
+}
+
+// setNode sets the value at a key path under a mapping node. Missing
+// mappings are created and an index one past the end of a list appends an
+// item. A replaced value keeps its comments.
+func setNode(node *yaml.Node, parts []keyPart, value *yaml.Node) error {
+	for i, part := range parts {
+		last := i == len(parts)-1
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a mapping", keyString(parts[:i]))
+		}
+
+		j := mappingIndex(node, part.name)
+		if j < 0 {
+			child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			switch {
+			case part.index >= 0:
+				child = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			case last:
+				child = value
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part.name}, child)
+			j = len(node.Content) - 2
+		} else if last && part.index < 0 {
+			replaceNode(node.Content[j+1], value)
+			return nil
+		}
+		node = node.Content[j+1]
+		if part.index < 0 {
+			continue
+		}
+
+		if node.Kind != yaml.SequenceNode {
+			return fmt.Errorf("%s is not a list", keyString(append(parts[:i:i], keyPart{part.name, -1})))
+		}
+		switch {
+		case part.index < len(node.Content) && last:
+			replaceNode(node.Content[part.index], value)
+			return nil
+		case part.index == len(node.Content) && last:
+			node.Content = append(node.Content, value)
+			return nil
+		case part.index == len(node.Content):
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+		case part.index > len(node.Content):
+			return fmt.Errorf("%s has %d items, can't set %s", keyString(append(parts[:i:i], keyPart{part.name, -1})), len(node.Content), keyString(parts[:i+1]))
+		}
+		node = node.Content[part.index]
+	}
+	return nil
+}
+
+// replaceNode replaces a value node with value, keeping the comments of
+// the old value and the quotes of single-line strings
+func replaceNode(node, value *yaml.Node) {
+	old := *node
+	*node = *value
+	node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	if old.Kind == yaml.ScalarNode && node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" && !strings.Contains(node.Value, "\n") {
+		node.Style = old.Style &^ (yaml.LiteralStyle | yaml.FoldedStyle)
+	}
+}
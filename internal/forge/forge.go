@@ -0,0 +1,366 @@
+package forge
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// Forge types
+const (
+	TypeGitHub  = "github"
+	TypeGitLab  = "gitlab"
+	TypeGitea   = "gitea"
+	TypeGeneric = "generic"
+)
+
+// Forge knows the URL layout of a git hosting service
+type Forge interface {
+	// Type returns the forge type, e.g. "github"
+	Type() string
+	// WebURL returns the browsable URL of a repository
+	WebURL(owner, repo string) string
+	// CloneURL returns the HTTPS clone URL of a repository
+	CloneURL(owner, repo string) string
+	// TarballURL returns the source archive URL of a tag, whose version
+	// (1.2.3 for tool/v1.2.3) is parsed with the configured tag format
+	TarballURL(owner, repo, tag, version string) string
+	// SSHURL returns the SSH clone URL of a repository
+	SSHURL(owner, repo string) string
+	// ArchiveAPIURL returns the API endpoint serving the source archive of a
+	// tag to authenticated clients, or "" if the forge has no such API
+	ArchiveAPIURL(owner, repo, tag string) string
+	// ReleaseAPIURL returns the API endpoint describing the release of a tag,
+	// or "" if the forge has no release API
+	ReleaseAPIURL(owner, repo, tag string) string
+	// APIURL returns the base URL of the forge's REST API, or "" if it has none
+	APIURL() string
+	// TokenAPIURL returns the API endpoint describing the authenticated user
+	// or token, used to check that a token is valid, or "" if unknown
+	TokenAPIURL() string
+	// Authorize adds token to req if req targets this forge
+	Authorize(req *http.Request, token string)
+	// GitAuthHeader returns the HTTP header value that authenticates git
+	// traffic to rawURL with token, or "" if rawURL is not on this forge
+	GitAuthHeader(rawURL string, token string) string
+}
+
+// New returns the forge of the given type. baseURL is the address of a
+// self-hosted instance and may be empty for github.com and gitlab.com.
+// tarballTemplate is only used by the generic forge.
+func New(forgeType, baseURL, tarballTemplate string) (Forge, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	switch forgeType {
+	case "", TypeGitHub:
+		if baseURL == "" {
+			return &github{web: "https://github.com", api: "https://api.github.com", uploads: "https://uploads.github.com"}, nil
+		}
+		// GitHub Enterprise Server
+		return &github{web: baseURL, api: baseURL + "/api/v3", uploads: baseURL + "/api/uploads"}, nil
+
+	case TypeGitLab:
+		if baseURL == "" {
+			baseURL = "https://gitlab.com"
+		}
+		return &gitlab{base: baseURL}, nil
+
+	case TypeGitea:
+		if baseURL == "" {
+			return nil, fmt.Errorf("forge.url is required for gitea")
+		}
+		return &gitea{base: baseURL}, nil
+
+	case TypeGeneric:
+		if baseURL == "" {
+			return nil, fmt.Errorf("forge.url is required for generic forges")
+		}
+		if tarballTemplate == "" {
+			return nil, fmt.Errorf("forge.tarball_url is required for generic forges")
+		}
+		tmpl, err := template.New("tarball_url").Parse(tarballTemplate)
+		if err == nil {
+			_, err = renderTarballURL(tmpl, baseURL, "owner", "repo", "v1.0.0", "1.0.0")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid forge.tarball_url: %w", err)
+		}
+		return &generic{base: baseURL, tarball: tmpl}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported forge type: %s (use github, gitlab, gitea, or generic)", forgeType)
+	}
+}
+
+// github is github.com or a GitHub Enterprise Server
+type github struct {
+	web     string
+	api     string
+	uploads string // release asset uploads
+}
+
+func (f *github) Type() string { return TypeGitHub }
+
+func (f *github) WebURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", f.web, owner, repo)
+}
+
+func (f *github) CloneURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s.git", f.web, owner, repo)
+}
+
+func (f *github) TarballURL(owner, repo, tag, version string) string {
+	return fmt.Sprintf("%s/%s/%s/archive/refs/tags/%s.tar.gz", f.web, owner, repo, tag)
+}
+
+func (f *github) SSHURL(owner, repo string) string {
+	return sshURL(f.web, owner, repo)
+}
+
+func (f *github) ArchiveAPIURL(owner, repo, tag string) string {
+	return fmt.Sprintf("%s/repos/%s/%s/tarball/%s", f.api, owner, repo, url.PathEscape(tag))
+}
+
+func (f *github) ReleaseAPIURL(owner, repo, tag string) string {
+	return fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", f.api, owner, repo, url.PathEscape(tag))
+}
+
+func (f *github) APIURL() string {
+	return f.api
+}
+
+func (f *github) TokenAPIURL() string {
+	return f.api + "/user"
+}
+
+func (f *github) Authorize(req *http.Request, token string) {
+	if token != "" && onHost(req.URL.String(), f.web, f.api, f.uploads) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func (f *github) GitAuthHeader(rawURL string, token string) string {
+	if token == "" || !onHost(rawURL, f.web) {
+		return ""
+	}
+	return basicAuth("x-access-token", token)
+}
+
+// gitlab is gitlab.com or a self-hosted GitLab
+type gitlab struct {
+	base string
+}
+
+func (f *gitlab) Type() string { return TypeGitLab }
+
+func (f *gitlab) WebURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", f.base, owner, repo)
+}
+
+func (f *gitlab) CloneURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s.git", f.base, owner, repo)
+}
+
+func (f *gitlab) TarballURL(owner, repo, tag, version string) string {
+	// Archive names can't contain slashes, GitLab replaces them with dashes
+	name := repo + "-" + strings.ReplaceAll(tag, "/", "-")
+	return fmt.Sprintf("%s/%s/%s/-/archive/%s/%s.tar.gz", f.base, owner, repo, tag, name)
+}
+
+func (f *gitlab) SSHURL(owner, repo string) string {
+	return sshURL(f.base, owner, repo)
+}
+
+func (f *gitlab) ArchiveAPIURL(owner, repo, tag string) string {
+	project := url.PathEscape(owner + "/" + repo)
+	return fmt.Sprintf("%s/api/v4/projects/%s/repository/archive.tar.gz?sha=%s", f.base, project, url.QueryEscape(tag))
+}
+
+func (f *gitlab) ReleaseAPIURL(owner, repo, tag string) string {
+	project := url.PathEscape(owner + "/" + repo)
+	return fmt.Sprintf("%s/api/v4/projects/%s/releases/%s", f.base, project, url.PathEscape(tag))
+}
+
+func (f *gitlab) APIURL() string {
+	return f.base + "/api/v4"
+}
+
+func (f *gitlab) TokenAPIURL() string {
+	return f.base + "/api/v4/personal_access_tokens/self"
+}
+
+func (f *gitlab) Authorize(req *http.Request, token string) {
+	if token != "" && onHost(req.URL.String(), f.base) {
+		req.Header.Set("PRIVATE-TOKEN", token)
+	}
+}
+
+func (f *gitlab) GitAuthHeader(rawURL string, token string) string {
+	if token == "" || !onHost(rawURL, f.base) {
+		return ""
+	}
+	return basicAuth("oauth2", token)
+}
+
+// gitea is a self-hosted Gitea or Forgejo
+type gitea struct {
+	base string
+}
+
+func (f *gitea) Type() string { return TypeGitea }
+
+func (f *gitea) WebURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", f.base, owner, repo)
+}
+
+func (f *gitea) CloneURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s.git", f.base, owner, repo)
+}
+
+func (f *gitea) TarballURL(owner, repo, tag, version string) string {
+	return fmt.Sprintf("%s/%s/%s/archive/%s.tar.gz", f.base, owner, repo, tag)
+}
+
+func (f *gitea) SSHURL(owner, repo string) string {
+	return sshURL(f.base, owner, repo)
+}
+
+func (f *gitea) ArchiveAPIURL(owner, repo, tag string) string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s/archive/%s.tar.gz", f.base, owner, repo, url.PathEscape(tag))
+}
+
+func (f *gitea) ReleaseAPIURL(owner, repo, tag string) string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s/releases/tags/%s", f.base, owner, repo, url.PathEscape(tag))
+}
+
+func (f *gitea) APIURL() string {
+	return f.base + "/api/v1"
+}
+
+func (f *gitea) TokenAPIURL() string {
+	return f.base + "/api/v1/user"
+}
+
+func (f *gitea) Authorize(req *http.Request, token string) {
+	if token != "" && onHost(req.URL.String(), f.base) {
+		req.Header.Set("Authorization", "token "+token)
+	}
+}
+
+func (f *gitea) GitAuthHeader(rawURL string, token string) string {
+	if token == "" || !onHost(rawURL, f.base) {
+		return ""
+	}
+	return "token " + token
+}
+
+// generic is any git host serving tarballs at a configurable URL
+type generic struct {
+	base    string
+	tarball *template.Template
+}
+
+func (f *generic) Type() string { return TypeGeneric }
+
+func (f *generic) WebURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", f.base, owner, repo)
+}
+
+func (f *generic) CloneURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s.git", f.base, owner, repo)
+}
+
+func (f *generic) TarballURL(owner, repo, tag, version string) string {
+	// The template was checked by New, so rendering can't fail
+	tarballURL, _ := renderTarballURL(f.tarball, f.base, owner, repo, tag, version)
+	return tarballURL
+}
+
+func (f *generic) SSHURL(owner, repo string) string {
+	return sshURL(f.base, owner, repo)
+}
+
+func (f *generic) ArchiveAPIURL(owner, repo, tag string) string {
+	return ""
+}
+
+func (f *generic) ReleaseAPIURL(owner, repo, tag string) string {
+	return ""
+}
+
+func (f *generic) APIURL() string {
+	return ""
+}
+
+func (f *generic) TokenAPIURL() string {
+	return ""
+}
+
+func (f *generic) Authorize(req *http.Request, token string) {
+	if token != "" && onHost(req.URL.String(), f.base) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func (f *generic) GitAuthHeader(rawURL string, token string) string {
+	if token == "" || !onHost(rawURL, f.base) {
+		return ""
+	}
+	return "Bearer " + token
+}
+
+// renderTarballURL renders a generic tarball URL template
+func renderTarballURL(tmpl *template.Template, base, owner, repo, tag, version string) (string, error) {
+	data := struct {
+		URL     string
+		Owner   string
+		Repo    string
+		Tag     string
+		Version string
+	}{
+		URL:     base,
+		Owner:   owner,
+		Repo:    repo,
+		Tag:     tag,
+		Version: version,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// sshURL returns the scp-style SSH clone URL for a repository on base
+func sshURL(base, owner, repo string) string {
+	host := base
+	if u, err := url.Parse(base); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("git@%s:%s/%s.git", host, owner, repo)
+}
+
+// onHost reports whether rawURL is an HTTPS URL on the same host as one of bases.
+// Tokens are never sent over plain HTTP or to foreign hosts.
+func onHost(rawURL string, bases ...string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	for _, base := range bases {
+		if b, err := url.Parse(base); err == nil && strings.EqualFold(b.Host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// basicAuth returns a Basic authorization header value
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
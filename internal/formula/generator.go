@@ -9,13 +9,25 @@ import (
 	"github.com/yejune/tobrew/internal/config"
 )
 
-const defaultTemplate = `class {{.ClassName}} < Formula
+const defaultTemplate = `{{if .DownloadStrategy}}require_relative "{{.StrategyRequire}}"
+
+{{end}}class {{.ClassName}} < Formula
   desc "{{.Description}}"
   homepage "{{.Homepage}}"
-  url "{{.URL}}"
+{{- if .Platforms}}
+  version "{{.Version}}"
+  license "{{.License}}"
+
+  {{.Platforms}}
+{{- else}}
+  url "{{.URL}}"{{if .DownloadStrategy}}, using: {{.DownloadStrategy}}{{end}}
+{{- if .Version}}
+  version "{{.Version}}"
+{{- end}}
   sha256 "{{.SHA256}}"
   license "{{.License}}"
   head "{{.HeadURL}}", branch: "main"
+{{- end}}
 {{if .DependsOn}}
   {{.DependsOn}}
 {{end}}
@@ -36,11 +48,17 @@ const defaultTemplate = `class {{.ClassName}} < Formula
 `
 
 type TemplateData struct {
-	ClassName     string
-	Description   string
-	Homepage      string
-	URL           string
-	SHA256        string
+	ClassName   string
+	Description string
+	Homepage    string
+	URL         string
+	SHA256      string
+
+	Version          string // explicit version, for URLs brew can't parse it from
+	DownloadStrategy string // custom strategy class for private repositories
+	StrategyRequire  string // require_relative path of the strategy file
+	Platforms        string // on_macos/on_linux blocks of binary archives, replacing url
+
 	License       string
 	HeadURL       string
 	DependsOn     string
@@ -49,22 +67,49 @@ type TemplateData struct {
 	Caveats       string
 }
 
-// Generate creates a Homebrew formula from config
-func Generate(cfg *config.Config, version string, sha256sum string) (string, error) {
+// Archive is a prebuilt binary archive of a release for one target
+type Archive struct {
+	Target string // Rust target triple, e.g. aarch64-apple-darwin
+	URL    string
+	SHA256 string
+}
+
+// Generate creates a Homebrew formula from config for a release tag.
+// With archives, the formula installs those instead of building the source.
+// formulaDir is the directory of the formula inside the tap, "" for the root.
+func Generate(cfg *config.Config, tag string, sha256sum string, archives []Archive, formulaDir string) (string, error) {
 	data := TemplateData{
 		ClassName:     cfg.GetFormulaName(),
 		Description:   cfg.Description,
 		Homepage:      cfg.Homepage,
-		URL:           cfg.GetTarballURL(version),
+		URL:           cfg.GetTarballURL(tag),
 		SHA256:        sha256sum,
 		License:       cfg.License,
-		HeadURL:       fmt.Sprintf("https://github.com/%s/%s.git", cfg.GitHub.User, cfg.GitHub.Repo),
-		DependsOn:     getDependency(cfg.Language),
+		HeadURL:       cfg.GetRepoURL(),
+		DependsOn:     dependsOn(cfg, len(archives) > 0),
 		InstallScript: indentScript(cfg.Formula.Install, 4),
 		TestScript:    indentScript(cfg.Formula.Test, 4),
 		Caveats:       indentLines(cfg.Formula.Caveats, 6),
 	}
 
+	if cfg.GitHub.Private {
+		data.Version = cfg.GetTagFormat().Version(tag)
+		data.DownloadStrategy = strategyClasses[cfg.GetForge().Type()]
+		data.StrategyRequire = strategyRequirePath(formulaDir)
+	}
+
+	if len(archives) > 0 {
+		platforms, err := platformBlocks(archives)
+		if err != nil {
+			return "", err
+		}
+		data.Platforms = platforms
+		data.Version = cfg.GetTagFormat().Version(tag)
+		if cfg.Formula.Install == "" {
+			data.InstallScript = indentScript(fmt.Sprintf(`bin.install "%s"`, cfg.RustBinary()), 4)
+		}
+	}
+
 	tmpl, err := template.New("formula").Parse(defaultTemplate)
 	if err != nil {
 		return "", err
@@ -116,33 +161,91 @@ func indentLines(text string, spaces int) string {
 	return strings.Join(result, "\n")
 }
 
-// getDependency returns the appropriate depends_on line for the language
-func getDependency(language string) string {
-	// Check for version-specific formats (e.g., php@8.4, python@3.11)
-	if strings.HasPrefix(language, "php@") {
-		return fmt.Sprintf(`depends_on "%s"`, language)
+// dependsOn returns the depends_on lines of the formula: the language's
+// dependency, unless a dependency of the same name is configured or the
+// formula installs prebuilt binaries, followed by the configured ones
+func dependsOn(cfg *config.Config, binaries bool) string {
+	deps := cfg.Formula.Dependencies
+	if dep, ok := LanguageDependency(cfg.Language); ok && !binaries && !hasDependency(deps, dep.Name) {
+		deps = append([]config.Dependency{dep}, deps...)
+	}
+
+	lines := make([]string, len(deps))
+	for i, dep := range deps {
+		lines[i] = dependsOnLine(dep)
+	}
+	return strings.Join(lines, "\n  ")
+}
+
+// platformBlocks renders the url and sha256 of each archive inside the
+// on_macos/on_linux and on_arm/on_intel blocks of its target
+func platformBlocks(archives []Archive) (string, error) {
+	byPlatform := map[config.Platform]Archive{}
+	for _, a := range archives {
+		p, ok := config.TargetPlatform(a.Target)
+		if !ok {
+			return "", fmt.Errorf("target %s is not a macOS or Linux target", a.Target)
+		}
+		byPlatform[p] = a
 	}
-	if strings.HasPrefix(language, "python@") {
-		return fmt.Sprintf(`depends_on "%s"`, language)
+
+	var blocks []string
+	for _, osName := range []string{"macos", "linux"} {
+		var arches []string
+		for _, arch := range []string{"arm", "intel"} {
+			if a, ok := byPlatform[config.Platform{OS: osName, Arch: arch}]; ok {
+				arches = append(arches, fmt.Sprintf("    on_%s do\n      url \"%s\"\n      sha256 \"%s\"\n    end", arch, a.URL, a.SHA256))
+			}
+		}
+		if len(arches) > 0 {
+			blocks = append(blocks, fmt.Sprintf("on_%s do\n%s\n  end", osName, strings.Join(arches, "\n")))
+		}
+	}
+	return strings.Join(blocks, "\n\n  "), nil
+}
+
+// dependsOnLine renders a dependency, e.g. depends_on "go" => :build
+func dependsOnLine(dep config.Dependency) string {
+	if dep.Type == "" {
+		return fmt.Sprintf(`depends_on "%s"`, dep.Name)
 	}
-	if strings.HasPrefix(language, "node@") {
-		return fmt.Sprintf(`depends_on "%s"`, language)
+	return fmt.Sprintf(`depends_on "%s" => :%s`, dep.Name, dep.Type)
+}
+
+// hasDependency reports whether deps contains a dependency on name
+func hasDependency(deps []config.Dependency, name string) bool {
+	for _, dep := range deps {
+		if dep.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LanguageDependency returns the dependency every formula of the language
+// has, false for prebuilt binaries
+func LanguageDependency(language string) (config.Dependency, bool) {
+	// Check for version-specific formats (e.g., php@8.4, python@3.11)
+	for _, prefix := range []string{"php@", "python@", "node@"} {
+		if strings.HasPrefix(language, prefix) {
+			return config.Dependency{Name: language}, true
+		}
 	}
 
 	switch language {
 	case "go":
-		return `depends_on "go" => :build`
+		return config.Dependency{Name: "go", Type: "build"}, true
 	case "rust":
-		return `depends_on "rust" => :build`
+		return config.Dependency{Name: "rust", Type: "build"}, true
 	case "python":
-		return `depends_on "python@3.11"`
+		return config.Dependency{Name: "python@3.11"}, true
 	case "node":
-		return `depends_on "node"`
+		return config.Dependency{Name: "node"}, true
 	case "php":
-		return `depends_on "php"`
+		return config.Dependency{Name: "php"}, true
 	case "binary":
-		return "" // No build dependency for prebuilt binaries
+		return config.Dependency{}, false // No build dependency for prebuilt binaries
 	default:
-		return `depends_on "go" => :build` // Default to Go
+		return config.Dependency{Name: "go", Type: "build"}, true // Default to Go
 	}
 }
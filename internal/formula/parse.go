@@ -0,0 +1,263 @@
+package formula
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/config"
+)
+
+var (
+	versionLineRe = regexp.MustCompile(`(?m)^\s*version\s+"([^"]+)"`)
+	urlLineRe     = regexp.MustCompile(`(?m)^\s*url\s+"([^"]+)"`)
+	urlVersionRe  = regexp.MustCompile(`v?\d+\.\d+\.\d+`)
+	sha256LineRe  = regexp.MustCompile(`(?m)^\s*sha256\s+"([0-9a-f]{64})"`)
+)
+
+// ParseVersion extracts the version of an existing formula, either from an
+// explicit version line or from the version embedded in its url
+func ParseVersion(content string) (string, bool) {
+	if m := versionLineRe.FindStringSubmatch(content); m != nil {
+		return m[1], true
+	}
+
+	if m := urlLineRe.FindStringSubmatch(content); m != nil {
+		// The last match wins: hosts or owners may contain version-like parts
+		matches := urlVersionRe.FindAllString(m[1], -1)
+		if len(matches) > 0 {
+			return matches[len(matches)-1], true
+		}
+	}
+
+	return "", false
+}
+
+// ParseSHA256 extracts the source checksum of an existing formula
+func ParseSHA256(content string) (string, bool) {
+	if m := sha256LineRe.FindStringSubmatch(content); m != nil {
+		return m[1], true
+	}
+	return "", false
+}
+
+// Parsed is what ParseFormula understood of a formula
+type Parsed struct {
+	Description  string
+	Homepage     string
+	URL          string
+	SHA256       string
+	License      string
+	Version      string
+	Dependencies []config.Dependency
+	Install      string
+	Test         string
+	Caveats      string
+
+	// Skipped lists the stanzas that have no tobrew equivalent, e.g. bottle
+	Skipped []string
+}
+
+var (
+	classLineRe    = regexp.MustCompile(`^(\s*)class\s+\w+\s*<\s*Formula\b`)
+	stanzaRe       = regexp.MustCompile(`^(\w+)\s*(.*)$`)
+	quotedRe       = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+	dependsOnRe    = regexp.MustCompile(`^"([^"]+)"(?:\s*=>\s*(.+))?$`)
+	dependsTypeRe  = regexp.MustCompile(`:(\w+)`)
+	heredocStartRe = regexp.MustCompile(`^<<[~-]?(\w+)$`)
+)
+
+// ParseFormula reads the metadata, dependencies and install, test and
+// caveats blocks of a hand-written formula. It understands the common
+// layout of formulas, one stanza per line, rather than all of Ruby.
+func ParseFormula(content string) (*Parsed, error) {
+	lines := strings.Split(content, "\n")
+
+	start := -1
+	for i, line := range lines {
+		if classLineRe.MatchString(line) {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("no formula class found")
+	}
+
+	p := &Parsed{}
+	indent := ""
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if indent == "" {
+			indent = lineIndent
+		}
+		if lineIndent != indent {
+			// The end of the class, or a continuation we don't understand
+			continue
+		}
+
+		// Blocks run up to the end at their own indentation
+		var body []string
+		if strings.HasSuffix(trimmed, " do") || strings.HasPrefix(trimmed, "def ") {
+			end := i + 1
+			for end < len(lines) && strings.TrimRight(lines[end], " \t") != indent+"end" {
+				end++
+			}
+			body = dedent(lines[i+1 : min(end, len(lines))])
+			i = end
+		}
+
+		m := stanzaRe.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		stanza, args := m[1], m[2]
+		switch {
+		case stanza == "desc":
+			p.Description = unquote(args)
+		case stanza == "homepage":
+			p.Homepage = unquote(args)
+		case stanza == "url":
+			p.URL = unquote(args)
+		case stanza == "sha256":
+			p.SHA256 = unquote(args)
+		case stanza == "version":
+			p.Version = unquote(args)
+		case stanza == "license":
+			p.License = parseLicense(args)
+			if p.License == "" {
+				p.Skipped = append(p.Skipped, trimmed)
+			}
+		case stanza == "depends_on":
+			if dep, ok := parseDependency(args); ok {
+				p.Dependencies = append(p.Dependencies, dep)
+			} else {
+				p.Skipped = append(p.Skipped, trimmed)
+			}
+		case stanza == "head":
+			// Generated from the repository
+		case trimmed == "def install":
+			p.Install = strings.Join(body, "\n")
+		case trimmed == "test do":
+			p.Test = strings.Join(body, "\n")
+		case trimmed == "def caveats":
+			if caveats, ok := parseCaveats(body); ok {
+				p.Caveats = caveats
+			} else {
+				p.Skipped = append(p.Skipped, "caveats (computed in Ruby)")
+			}
+		case body != nil:
+			p.Skipped = append(p.Skipped, strings.TrimSuffix(trimmed, " do"))
+		default:
+			p.Skipped = append(p.Skipped, trimmed)
+		}
+	}
+
+	if p.Version == "" {
+		p.Version, _ = ParseVersion(content)
+	}
+	return p, nil
+}
+
+// unquote returns the first string literal of args
+func unquote(args string) string {
+	m := quotedRe.FindStringSubmatch(args)
+	if m == nil {
+		return ""
+	}
+	if s, err := strconv.Unquote(`"` + m[1] + `"`); err == nil {
+		return s
+	}
+	return m[1]
+}
+
+// parseLicense converts a license stanza to an SPDX expression, e.g.
+// any_of: ["MIT", "Apache-2.0"] to "MIT OR Apache-2.0"
+func parseLicense(args string) string {
+	var ids []string
+	for _, m := range quotedRe.FindAllStringSubmatch(args, -1) {
+		ids = append(ids, m[1])
+	}
+	switch {
+	case len(ids) == 1 && strings.HasPrefix(args, `"`):
+		return ids[0]
+	case len(ids) > 0 && strings.HasPrefix(args, "any_of:") && !strings.Contains(args, "{"):
+		return strings.Join(ids, " OR ")
+	case len(ids) > 0 && strings.HasPrefix(args, "all_of:") && !strings.Contains(args, "{"):
+		return strings.Join(ids, " AND ")
+	}
+	return ""
+}
+
+// parseDependency parses the arguments of depends_on, e.g. "go" => :build.
+// Requirements such as :macos and dependencies with several types are not
+// supported.
+func parseDependency(args string) (config.Dependency, bool) {
+	m := dependsOnRe.FindStringSubmatch(args)
+	if m == nil {
+		return config.Dependency{}, false
+	}
+	dep := config.Dependency{Name: m[1]}
+	if m[2] == "" {
+		return dep, true
+	}
+	types := dependsTypeRe.FindAllStringSubmatch(m[2], -1)
+	if len(types) != 1 || !slices.Contains(config.DependencyTypes, types[0][1]) {
+		return config.Dependency{}, false
+	}
+	dep.Type = types[0][1]
+	return dep, true
+}
+
+// parseCaveats returns the text of a caveats method made of a heredoc or a
+// string literal
+func parseCaveats(body []string) (string, bool) {
+	if len(body) == 0 {
+		return "", false
+	}
+	first := strings.TrimSpace(body[0])
+	if len(body) == 1 && strings.HasPrefix(first, `"`) && strings.HasSuffix(first, `"`) {
+		return unquote(first), true
+	}
+
+	m := heredocStartRe.FindStringSubmatch(first)
+	if m == nil || strings.TrimSpace(body[len(body)-1]) != m[1] {
+		return "", false
+	}
+	text := strings.Join(dedent(body[1:len(body)-1]), "\n")
+	if strings.Contains(text, "#{") {
+		// Interpolation needs Ruby
+		return "", false
+	}
+	return text, true
+}
+
+// dedent removes the indentation common to all non-blank lines
+func dedent(lines []string) []string {
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if common < 0 || n < common {
+			common = n
+		}
+	}
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		result[i] = strings.TrimRight(line[common:], " \t")
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package formula
+
+import (
+	"path"
+
+	"github.com/yejune/tobrew/internal/forge"
+)
+
+// StrategyPath is where the private download strategy lives inside a tap.
+// It is kept out of the formula directories so Homebrew doesn't load it as a formula.
+const StrategyPath = "lib/private_download_strategy.rb"
+
+// DownloadStrategy is bundled into taps of private repositories. Formulas
+// reference one of its classes with `using:` so brew sends the user's API
+// token when downloading the source archive.
+const DownloadStrategy = `# Generated by tobrew - do not edit.
+# Download strategies for formulas of private repositories.
+require "download_strategy"
+
+class TobrewPrivateDownloadStrategy < CurlDownloadStrategy
+  def token_env
+    raise NotImplementedError
+  end
+
+  def auth_header(_token)
+    raise NotImplementedError
+  end
+
+  private
+
+  def _fetch(url:, resolved_url:, timeout:)
+    token = ENV.fetch(token_env, nil)
+    raise CurlDownloadStrategyError, "Environment variable #{token_env} is required to download #{url}" if token.to_s.empty?
+
+    curl_download url, "--header", auth_header(token), to: temporary_path, timeout: timeout
+  end
+end
+
+class GitHubPrivateRepositoryDownloadStrategy < TobrewPrivateDownloadStrategy
+  def token_env
+    "HOMEBREW_GITHUB_API_TOKEN"
+  end
+
+  def auth_header(token)
+    "Authorization: Bearer #{token}"
+  end
+end
+
+class GitLabPrivateRepositoryDownloadStrategy < TobrewPrivateDownloadStrategy
+  def token_env
+    "HOMEBREW_GITLAB_API_TOKEN"
+  end
+
+  def auth_header(token)
+    "PRIVATE-TOKEN: #{token}"
+  end
+end
+
+class GiteaPrivateRepositoryDownloadStrategy < TobrewPrivateDownloadStrategy
+  def token_env
+    "HOMEBREW_GITEA_API_TOKEN"
+  end
+
+  def auth_header(token)
+    "Authorization: token #{token}"
+  end
+end
+`
+
+// strategyClasses maps forge types to their download strategy class
+var strategyClasses = map[string]string{
+	forge.TypeGitHub: "GitHubPrivateRepositoryDownloadStrategy",
+	forge.TypeGitLab: "GitLabPrivateRepositoryDownloadStrategy",
+	forge.TypeGitea:  "GiteaPrivateRepositoryDownloadStrategy",
+}
+
+// StrategyTokenEnv returns the variable brew users must set to install
+// formulas of private repositories hosted on the given forge
+func StrategyTokenEnv(forgeType string) string {
+	switch forgeType {
+	case forge.TypeGitLab:
+		return "HOMEBREW_GITLAB_API_TOKEN"
+	case forge.TypeGitea:
+		return "HOMEBREW_GITEA_API_TOKEN"
+	default:
+		return "HOMEBREW_GITHUB_API_TOKEN"
+	}
+}
+
+// strategyRequirePath returns the require_relative path of the strategy
+// file for a formula stored in formulaDir (relative to the tap root)
+func strategyRequirePath(formulaDir string) string {
+	up := ""
+	for dir := path.Clean(formulaDir); dir != "." && dir != "/" && dir != ""; dir = path.Dir(dir) {
+		up += "../"
+	}
+	return up + "lib/private_download_strategy"
+}
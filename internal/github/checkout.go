@@ -0,0 +1,166 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yejune/tobrew/internal/config"
+)
+
+const (
+	lockPollInterval = 500 * time.Millisecond
+	staleLockAge     = 30 * time.Minute
+	// lockTimeout outlasts staleLockAge, so a waiter can take over any
+	// abandoned lock before giving up
+	lockTimeout = staleLockAge + 5*time.Minute
+)
+
+// tapCacheDir returns the persistent checkout directory for a tap
+func tapCacheDir(tap config.TapConfig) string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "tobrew", "taps", tap.Owner, tap.Repo)
+}
+
+// prepareCheckout makes dir a clean checkout of the tap branch.
+// An existing checkout is reused with fetch + hard reset; it is cloned
+// again when missing, broken or when fresh is set.
+func prepareCheckout(dir string, tapURL string, branch string, env []string, fresh bool) error {
+	if !fresh {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			if err := refreshCheckout(dir, tapURL, branch, env); err == nil {
+				return nil
+			}
+			fmt.Fprintln(os.Stderr, "   Cached tap checkout is unusable, cloning again...")
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove cached tap checkout: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create tap cache directory: %w", err)
+	}
+	if err := runCmd(filepath.Dir(dir), env, "git", "clone", "--branch", branch, tapURL, dir); err != nil {
+		return fmt.Errorf("failed to clone tap repo: %w", err)
+	}
+	return nil
+}
+
+// refreshCheckout resets an existing checkout to the remote branch
+func refreshCheckout(dir string, tapURL string, branch string, env []string) error {
+	steps := [][]string{
+		{"remote", "set-url", "origin", tapURL},
+		{"fetch", "--prune", "origin", branch},
+		{"checkout", "-q", "-B", branch, "origin/" + branch},
+		{"reset", "-q", "--hard", "origin/" + branch},
+		{"clean", "-q", "-fdx"},
+	}
+	for _, args := range steps {
+		if err := runCmd(dir, env, "git", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockDir takes an exclusive lock next to dir so concurrent releases don't
+// share a tap checkout. The returned function releases the lock.
+func lockDir(dir string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create tap cache directory: %w", err)
+	}
+
+	lockPath := dir + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	waiting := false
+
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("pid %d on %s", os.Getpid(), host)
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintln(f, owner)
+			f.Close()
+			return func() {
+				// A lock taken over as stale belongs to someone else now
+				if lockOwner(lockPath) == owner {
+					os.Remove(lockPath)
+				}
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		// A lock left behind by a crashed process is taken over
+		if info, statErr := os.Stat(lockPath); statErr == nil && lockIsStale(lockPath, info, host) {
+			takeOverLock(lockPath, info)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for tap lock %s (held by %s)", lockPath, lockOwner(lockPath))
+		}
+		if !waiting {
+			fmt.Fprintf(os.Stderr, "   Waiting for another tobrew process (%s) to release the tap...\n", lockOwner(lockPath))
+			waiting = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// lockIsStale reports whether a lock was abandoned: its process is gone, or,
+// when that can't be checked because it ran on another host, the lock is
+// older than staleLockAge
+func lockIsStale(lockPath string, info os.FileInfo, host string) bool {
+	var pid int
+	var lockHost string
+	if _, err := fmt.Sscanf(lockOwner(lockPath), "pid %d on %s", &pid, &lockHost); err == nil && lockHost == host {
+		return processGone(pid)
+	}
+	return time.Since(info.ModTime()) > staleLockAge
+}
+
+// processGone reports whether no process with pid runs on this host
+func processGone(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return errors.Is(err, os.ErrProcessDone) || errors.Is(err, syscall.ESRCH)
+}
+
+// takeOverLock removes the stale lock file described by stale. The file is
+// first renamed away, which only one of several waiters can do, and removed
+// only if it is still the file found stale: a fresh lock another waiter
+// created in the meantime is put back.
+func takeOverLock(lockPath string, stale os.FileInfo) {
+	moved := fmt.Sprintf("%s.stale-%d", lockPath, os.Getpid())
+	if err := os.Rename(lockPath, moved); err != nil {
+		return
+	}
+	if info, err := os.Stat(moved); err == nil && !os.SameFile(info, stale) {
+		// Link, unlike rename, doesn't replace a lock created since
+		os.Link(moved, lockPath)
+	}
+	os.Remove(moved)
+}
+
+// lockOwner returns the owner recorded in a lock file, e.g. pid 123 on host
+func lockOwner(lockPath string) string {
+	data, err := os.ReadFile(lockPath)
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,143 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/forge"
+)
+
+// release is the part of a GitHub release the asset upload needs
+type release struct {
+	ID        int64  `json:"id"`
+	UploadURL string `json:"upload_url"` // RFC 6570 template, e.g. .../assets{?name,label}
+	Assets    []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+// asset is an uploaded release asset
+type asset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+// UploadAssets attaches files to the GitHub release of tag, creating the
+// release if there is none yet, and returns the download URL of each file
+// by base name. Assets of the same name, left by an earlier attempt, are
+// replaced.
+func UploadAssets(cfg *config.Config, tag string, files []string) (map[string]string, error) {
+	f := cfg.GetForge()
+	if f.Type() != forge.TypeGitHub {
+		return nil, fmt.Errorf("release assets are only supported on GitHub")
+	}
+	token := cfg.GetToken()
+	if token == "" {
+		return nil, fmt.Errorf("a GitHub token is required to upload release assets, set GITHUB_TOKEN")
+	}
+
+	rel, err := ensureRelease(f, token, cfg, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := map[string]string{}
+	for _, file := range files {
+		name := filepath.Base(file)
+		for _, existing := range rel.Assets {
+			if existing.Name != name {
+				continue
+			}
+			assetURL := fmt.Sprintf("%s/repos/%s/%s/releases/assets/%d", f.APIURL(), cfg.GitHub.User, cfg.GitHub.Repo, existing.ID)
+			resp, err := apiRequest(f, token, http.MethodDelete, assetURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				return nil, fmt.Errorf("failed to replace asset %s: HTTP %d", name, resp.StatusCode)
+			}
+		}
+
+		uploaded, err := uploadAsset(f, token, rel, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", name, err)
+		}
+		urls[name] = uploaded.DownloadURL
+	}
+	return urls, nil
+}
+
+// ensureRelease returns the release of tag, creating it if needed
+func ensureRelease(f forge.Forge, token string, cfg *config.Config, tag string) (*release, error) {
+	resp, err := apiRequest(f, token, http.MethodGet, cfg.GetReleaseAPIURL(tag), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		createURL := fmt.Sprintf("%s/repos/%s/%s/releases", f.APIURL(), cfg.GitHub.User, cfg.GitHub.Repo)
+		resp, err = apiRequest(f, token, http.MethodPost, createURL, map[string]interface{}{
+			"tag_name": tag,
+			"name":     tag,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to get release %s: HTTP %d", tag, resp.StatusCode)
+	}
+	var rel release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("failed to parse release %s: %w", tag, err)
+	}
+	return &rel, nil
+}
+
+// uploadAsset uploads a file to a release
+func uploadAsset(f forge.Forge, token string, rel *release, file string) (*asset, error) {
+	data, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	info, err := data.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	uploadURL, _, _ := strings.Cut(rel.UploadURL, "{")
+	req, err := http.NewRequest(http.MethodPost, uploadURL+"?name="+url.QueryEscape(filepath.Base(file)), data)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/gzip")
+	f.Authorize(req, token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var uploaded asset
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return nil, err
+	}
+	return &uploaded, nil
+}
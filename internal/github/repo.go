@@ -0,0 +1,95 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/forge"
+)
+
+// EnsureTapRepo creates the tap repository through the GitHub API if it
+// doesn't exist yet, and reports whether it was created. The repository is
+// initialized with a README so that its default branch exists.
+func EnsureTapRepo(cfg *config.Config, tap config.TapConfig) (bool, error) {
+	f := cfg.GetForge()
+	if f.Type() != forge.TypeGitHub {
+		return false, fmt.Errorf("creating taps is only supported on GitHub, create %s by hand", tap)
+	}
+	token := cfg.GetToken()
+	if token == "" {
+		return false, fmt.Errorf("a GitHub token is required to create %s, set GITHUB_TOKEN", tap)
+	}
+	api := f.APIURL()
+
+	resp, err := apiRequest(f, token, http.MethodGet, fmt.Sprintf("%s/repos/%s/%s", api, tap.Owner, tap.Repo), nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return false, nil
+	case http.StatusNotFound:
+	default:
+		return false, fmt.Errorf("failed to look up %s: HTTP %d", tap, resp.StatusCode)
+	}
+
+	// Repositories of the token's user and of organizations are created differently
+	var user struct {
+		Login string `json:"login"`
+	}
+	resp, err = apiRequest(f, token, http.MethodGet, api+"/user", nil)
+	if err != nil {
+		return false, err
+	}
+	err = json.NewDecoder(resp.Body).Decode(&user)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || err != nil {
+		return false, fmt.Errorf("failed to identify the token's user: HTTP %d", resp.StatusCode)
+	}
+
+	createURL := fmt.Sprintf("%s/orgs/%s/repos", api, tap.Owner)
+	if strings.EqualFold(user.Login, tap.Owner) {
+		createURL = api + "/user/repos"
+	}
+
+	body := map[string]interface{}{
+		"name":        tap.Repo,
+		"description": "Homebrew formulae",
+		"auto_init":   true,
+	}
+	resp, err = apiRequest(f, token, http.MethodPost, createURL, body)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return false, fmt.Errorf("failed to create %s: HTTP %d", tap, resp.StatusCode)
+	}
+	return true, nil
+}
+
+// apiRequest sends an authorized JSON request to the forge API
+func apiRequest(f forge.Forge, token, method, url string, body interface{}) (*http.Response, error) {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	f.Authorize(req, token)
+	return http.DefaultClient.Do(req)
+}
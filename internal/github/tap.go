@@ -1,74 +1,196 @@
 package github
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/yejune/tobrew/internal/auth"
 	"github.com/yejune/tobrew/internal/config"
+	"github.com/yejune/tobrew/internal/formula"
 )
 
-// UpdateTap updates the homebrew-tap repository with the formula
-func UpdateTap(cfg *config.Config, formulaContent string, version string) error {
+// maxPushAttempts bounds the rebase-and-retry loop on rejected pushes
+const maxPushAttempts = 3
+
+// Options controls how the tap checkout is prepared
+type Options struct {
+	FreshClone bool // discard the cached checkout and clone again
+}
+
+// UpdateTap updates a homebrew tap repository with the formula and returns
+// the SHA of the pushed tap commit
+func UpdateTap(cfg *config.Config, tap config.TapConfig, formulaContent string, version string, opts Options) (string, error) {
 	commitMsg := fmt.Sprintf("Update %s to %s", cfg.Name, version)
-	return UpdateTapWithMessage(cfg, formulaContent, commitMsg)
+
+	pushBranch := tap.Branch
+	if tap.Mode == config.TapModeBranch {
+		pushBranch = fmt.Sprintf("tobrew/%s-%s", cfg.Name, version)
+	}
+
+	return updateTap(cfg, tap, formulaContent, version, commitMsg, pushBranch, opts)
 }
 
-// UpdateTapWithMessage updates tap with custom commit message
-func UpdateTapWithMessage(cfg *config.Config, formulaContent string, commitMsg string) error {
-	// Create temporary directory
-	tmpDir := filepath.Join(os.TempDir(), "homebrew-tap-"+cfg.GitHub.TapRepo)
+// updateTap commits the formula to the cached tap checkout and pushes it to pushBranch
+func updateTap(cfg *config.Config, tap config.TapConfig, formulaContent string, version string, commitMsg string, pushBranch string, opts Options) (string, error) {
+	tapDir := tapCacheDir(tap)
 
-	// Clean up old tmp dir if exists
-	os.RemoveAll(tmpDir)
+	// Serialize concurrent releases sharing the same checkout
+	unlock, err := lockDir(tapDir)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
 
-	tapURL := cfg.GetTapRepoURL()
+	// Authenticate git with the forge token when one is available
+	tapURL := cfg.GetTapRepoURL(tap)
+	env := auth.GitEnv(cfg.GetForge(), cfg.GetToken(), tapURL)
 
-	// Clone existing repo
-	if err := runCmd(os.TempDir(), "git", "clone", tapURL, tmpDir); err != nil {
-		return fmt.Errorf("failed to clone tap repo: %w", err)
+	if err := prepareCheckout(tapDir, tapURL, tap.Branch, env, opts.FreshClone); err != nil {
+		return "", err
 	}
-	defer os.RemoveAll(tmpDir)
 
-	// Count existing files before modification
-	existingFiles, _ := filepath.Glob(filepath.Join(tmpDir, "*.rb"))
-	initialFileCount := len(existingFiles)
+	formulaPath := tap.FormulaPath(cfg.Name)
+	formulaFile := filepath.Join(tapDir, filepath.FromSlash(formulaPath))
+	formulaDir := filepath.Dir(formulaFile)
 
 	// Write formula (update or create)
-	formulaFile := filepath.Join(tmpDir, cfg.Name+".rb")
+	if err := os.MkdirAll(formulaDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create formula directory: %w", err)
+	}
 	if err := os.WriteFile(formulaFile, []byte(formulaContent), 0644); err != nil {
-		return fmt.Errorf("failed to write formula: %w", err)
+		return "", fmt.Errorf("failed to write formula: %w", err)
+	}
+
+	// Private repositories need the download strategy bundled into the tap
+	paths := []string{formulaPath}
+	var supportPaths []string
+	if cfg.GitHub.Private {
+		strategyFile := filepath.Join(tapDir, filepath.FromSlash(formula.StrategyPath))
+		if err := os.MkdirAll(filepath.Dir(strategyFile), 0755); err != nil {
+			return "", fmt.Errorf("failed to create strategy directory: %w", err)
+		}
+		if err := os.WriteFile(strategyFile, []byte(formula.DownloadStrategy), 0644); err != nil {
+			return "", fmt.Errorf("failed to write download strategy: %w", err)
+		}
+		paths = append(paths, formula.StrategyPath)
+		supportPaths = append(supportPaths, formula.StrategyPath)
 	}
 
 	// Git add and commit
-	if err := runCmd(tmpDir, "git", "add", cfg.Name+".rb"); err != nil {
-		return err
+	if err := runCmd(tapDir, nil, "git", append([]string{"add"}, paths...)...); err != nil {
+		return "", err
+	}
+
+	if pushBranch != tap.Branch {
+		if err := runCmd(tapDir, nil, "git", "checkout", "-B", pushBranch); err != nil {
+			return "", err
+		}
+	}
+
+	if err := runCmd(tapDir, nil, "git", "commit", "-m", commitMsg); err != nil {
+		return "", err
 	}
 
-	if err := runCmd(tmpDir, "git", "commit", "-m", commitMsg); err != nil {
-		return err
+	// Safety check: ensure the commit only touches our formula
+	if err := verifyTapCommit(tapDir, formulaPath, version, supportPaths...); err != nil {
+		return "", err
 	}
 
-	// Safety check: ensure we're not accidentally deleting other formulas
-	finalFiles, _ := filepath.Glob(filepath.Join(tmpDir, "*.rb"))
-	if len(finalFiles) < initialFileCount {
-		return fmt.Errorf("safety check failed: formula count decreased from %d to %d, aborting push", initialFileCount, len(finalFiles))
+	// Push (no force). Another release may have pushed to the same tap in the
+	// meantime; since formulas live in separate files, rebase onto it and retry.
+	for attempt := 1; ; attempt++ {
+		output, err := runCmdOutput(tapDir, env, "git", "push", "origin", pushBranch)
+		if err == nil {
+			sha, _ := gitOutput(tapDir, "rev-parse", "HEAD")
+			return strings.TrimSpace(sha), nil
+		}
+		if !isNonFastForward(output) || attempt == maxPushAttempts {
+			return "", fmt.Errorf("failed to push tap: %w", err)
+		}
+
+		fmt.Printf("   Tap was updated concurrently, rebasing (attempt %d/%d)...\n", attempt+1, maxPushAttempts)
+		if err := runCmd(tapDir, env, "git", "pull", "--rebase", "origin", pushBranch); err != nil {
+			runCmd(tapDir, nil, "git", "rebase", "--abort")
+			return "", fmt.Errorf("failed to rebase onto remote tap: %w", err)
+		}
+		if err := verifyTapCommit(tapDir, formulaPath, version, supportPaths...); err != nil {
+			return "", err
+		}
+	}
+}
+
+// ReadFormula returns the formula of the project currently published in a
+// tap, or "" if the tap has no formula for it yet
+func ReadFormula(cfg *config.Config, tap config.TapConfig) (string, error) {
+	content, _, err := FindFormula(cfg, tap, []string{tap.Directory})
+	return content, err
+}
+
+// FindFormula looks for the formula of the project in each of dirs of a
+// tap, checked out once, and returns the first found and its directory, or
+// "" if none of them has it
+func FindFormula(cfg *config.Config, tap config.TapConfig, dirs []string) (string, string, error) {
+	tapDir := tapCacheDir(tap)
+
+	unlock, err := lockDir(tapDir)
+	if err != nil {
+		return "", "", err
 	}
+	defer unlock()
 
-	// Push (no force)
-	if err := runCmd(tmpDir, "git", "push", "origin", "main"); err != nil {
-		return err
+	tapURL := cfg.GetTapRepoURL(tap)
+	env := auth.GitEnv(cfg.GetForge(), cfg.GetToken(), tapURL)
+	if err := prepareCheckout(tapDir, tapURL, tap.Branch, env, false); err != nil {
+		return "", "", err
 	}
 
-	return nil
+	for _, dir := range dirs {
+		tap.Directory = dir
+		data, err := os.ReadFile(filepath.Join(tapDir, filepath.FromSlash(tap.FormulaPath(cfg.Name))))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read formula: %w", err)
+		}
+		return string(data), dir, nil
+	}
+	return "", "", nil
+}
+
+// isNonFastForward reports whether git push output is a non-fast-forward rejection
+func isNonFastForward(output string) bool {
+	return strings.Contains(output, "non-fast-forward") ||
+		strings.Contains(output, "fetch first") ||
+		strings.Contains(output, "[rejected]")
 }
 
-// runCmd executes a command in a specific directory
-func runCmd(dir string, name string, args ...string) error {
+// runCmd executes a command in a specific directory.
+// A nil env inherits the current environment. Its output goes to stderr,
+// keeping stdout for results such as tobrew status --json.
+func runCmd(dir string, env []string, name string, args ...string) error {
 	cmd := exec.Command(name, args...)
 	cmd.Dir = dir
-	cmd.Stdout = os.Stdout
+	cmd.Env = env
+	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
+
+// runCmdOutput executes a command like runCmd, its output also going to
+// stderr, and returns its combined output
+func runCmdOutput(dir string, env []string, name string, args ...string) (string, error) {
+	var buf bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = io.MultiWriter(os.Stderr, &buf)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &buf)
+	err := cmd.Run()
+	return buf.String(), err
+}
@@ -0,0 +1,62 @@
+package github
+
+import "testing"
+
+func TestIsNonFastForward(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{
+			name: "fetch first",
+			output: `To https://github.com/acme/homebrew-tap.git
+ ! [rejected]        main -> main (fetch first)
+error: failed to push some refs to 'https://github.com/acme/homebrew-tap.git'
+hint: Updates were rejected because the remote contains work that you do
+hint: not have locally.`,
+			want: true,
+		},
+		{
+			name: "non-fast-forward",
+			output: `To github.com:acme/homebrew-tap.git
+ ! [rejected]        main -> main (non-fast-forward)
+error: failed to push some refs to 'github.com:acme/homebrew-tap.git'
+hint: Updates were rejected because the tip of your current branch is behind`,
+			want: true,
+		},
+		{
+			name: "success",
+			output: `To https://github.com/acme/homebrew-tap.git
+   1a2b3c4..5d6e7f8  main -> main`,
+			want: false,
+		},
+		{
+			name: "authentication failed",
+			output: `remote: Invalid username or password.
+fatal: Authentication failed for 'https://github.com/acme/homebrew-tap.git/'`,
+			want: false,
+		},
+		{
+			name: "protected branch",
+			output: `remote: error: GH006: Protected branch update failed for refs/heads/main.
+To https://github.com/acme/homebrew-tap.git
+ ! [remote rejected] main -> main (protected branch hook declined)
+error: failed to push some refs to 'https://github.com/acme/homebrew-tap.git'`,
+			want: false,
+		},
+		{
+			name:   "network error",
+			output: `fatal: unable to access 'https://github.com/acme/homebrew-tap.git/': Could not resolve host: github.com`,
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNonFastForward(tt.output); got != tt.want {
+				t.Errorf("isNonFastForward() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
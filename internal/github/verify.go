@@ -0,0 +1,93 @@
+package github
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/yejune/tobrew/internal/formula"
+	"github.com/yejune/tobrew/internal/version"
+)
+
+// verifyTapCommit checks the commit at HEAD before it is pushed: it must add
+// or modify exactly formulaPath, touch nothing else but the given support
+// files, and not downgrade the version of an existing formula.
+func verifyTapCommit(dir string, formulaPath string, newVersion string, supportPaths ...string) error {
+	changes, err := commitChanges(dir)
+	if err != nil {
+		return fmt.Errorf("safety check failed: %w", err)
+	}
+
+	var offending []string
+	touched := false
+	for _, change := range changes {
+		addOrModify := change.status == "A" || change.status == "M"
+		if change.path == formulaPath && addOrModify {
+			touched = true
+			continue
+		}
+		if addOrModify && slices.Contains(supportPaths, change.path) {
+			continue
+		}
+		offending = append(offending, fmt.Sprintf("%s\t%s", change.status, change.path))
+	}
+	if len(offending) > 0 {
+		return fmt.Errorf("safety check failed: commit touches paths other than %s, aborting push:\n  %s",
+			formulaPath, strings.Join(offending, "\n  "))
+	}
+	if !touched {
+		return fmt.Errorf("safety check failed: commit does not change %s, aborting push", formulaPath)
+	}
+
+	// Refuse to replace a newer formula with an older one
+	previous, err := gitOutput(dir, "show", "HEAD~1:"+formulaPath)
+	if err != nil {
+		return nil // new formula
+	}
+	if oldVersion, ok := formula.ParseVersion(previous); ok && version.Compare(newVersion, oldVersion) < 0 {
+		return fmt.Errorf("safety check failed: %s would be downgraded from %s to %s, aborting push",
+			formulaPath, oldVersion, newVersion)
+	}
+
+	return nil
+}
+
+type pathChange struct {
+	status string
+	path   string
+}
+
+// commitChanges lists the paths changed by the commit at HEAD
+func commitChanges(dir string) ([]pathChange, error) {
+	output, err := gitOutput(dir, "diff", "--name-status", "HEAD~1", "HEAD")
+	if err != nil {
+		// First commit of the tap: there is no parent to diff against
+		output, err = gitOutput(dir, "diff-tree", "--root", "--no-commit-id", "--name-status", "-r", "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list changed files: %w", err)
+		}
+	}
+
+	var changes []pathChange
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		// Renames and copies (R100, C75) list source and destination
+		status := fields[0][:1]
+		for _, path := range fields[1:] {
+			changes = append(changes, pathChange{status: status, path: path})
+		}
+	}
+	return changes, nil
+}
+
+// gitOutput runs a git command in dir and returns its stdout
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	return string(output), err
+}
@@ -0,0 +1,223 @@
+package github
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/tobrew/internal/formula"
+)
+
+const testFormulaPath = "Formula/tool.rb"
+
+// testFormula returns a formula of the given version
+func testFormula(version string) string {
+	return "class Tool < Formula\n" +
+		`  url "https://github.com/acme/tool/archive/refs/tags/` + version + ".tar.gz\"\n" +
+		"end\n"
+}
+
+// testTap creates a git repository whose first commit writes files
+func testTap(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	git(t, dir, "init", "-q", "-b", "main")
+	if len(files) > 0 {
+		commitFiles(t, dir, files, nil)
+	}
+	return dir
+}
+
+// commitFiles writes and removes files and commits the result
+func commitFiles(t *testing.T, dir string, write map[string]string, remove []string) {
+	t.Helper()
+	for path, content := range write {
+		file := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range remove {
+		git(t, dir, "rm", "-q", path)
+	}
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "test")
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+func TestVerifyTapCommitPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial map[string]string // first commit, none for a new tap
+		write   map[string]string
+		remove  []string
+		support []string
+		wantErr string
+	}{
+		{
+			name:  "first formula of a new tap",
+			write: map[string]string{testFormulaPath: testFormula("v1.0.0")},
+		},
+		{
+			name:    "formula added",
+			initial: map[string]string{"README.md": "tap"},
+			write:   map[string]string{testFormulaPath: testFormula("v1.0.0")},
+		},
+		{
+			name:    "formula modified",
+			initial: map[string]string{testFormulaPath: testFormula("v1.0.0")},
+			write:   map[string]string{testFormulaPath: testFormula("v1.0.1")},
+		},
+		{
+			name:    "support file alongside",
+			initial: map[string]string{"README.md": "tap"},
+			write: map[string]string{
+				testFormulaPath:      testFormula("v1.0.0"),
+				formula.StrategyPath: formula.DownloadStrategy,
+			},
+			support: []string{formula.StrategyPath},
+		},
+		{
+			name:    "support file not allowed",
+			initial: map[string]string{"README.md": "tap"},
+			write: map[string]string{
+				testFormulaPath:      testFormula("v1.0.0"),
+				formula.StrategyPath: formula.DownloadStrategy,
+			},
+			wantErr: "touches paths other than",
+		},
+		{
+			name:    "other formula modified",
+			initial: map[string]string{"Formula/other.rb": testFormula("v2.0.0")},
+			write: map[string]string{
+				testFormulaPath:    testFormula("v1.0.0"),
+				"Formula/other.rb": testFormula("v2.0.1"),
+			},
+			wantErr: "M\tFormula/other.rb",
+		},
+		{
+			name: "other file deleted",
+			initial: map[string]string{
+				testFormulaPath: testFormula("v1.0.0"),
+				"README.md":     "tap",
+			},
+			write:   map[string]string{testFormulaPath: testFormula("v1.0.1")},
+			remove:  []string{"README.md"},
+			wantErr: "D\tREADME.md",
+		},
+		{
+			name: "formula deleted",
+			initial: map[string]string{
+				testFormulaPath: testFormula("v1.0.0"),
+				"README.md":     "tap",
+			},
+			write:   map[string]string{"README.md": "changed"},
+			remove:  []string{testFormulaPath},
+			wantErr: "touches paths other than",
+		},
+		{
+			name: "support file deleted",
+			initial: map[string]string{
+				testFormulaPath:      testFormula("v1.0.0"),
+				formula.StrategyPath: formula.DownloadStrategy,
+			},
+			write:   map[string]string{testFormulaPath: testFormula("v1.0.1")},
+			remove:  []string{formula.StrategyPath},
+			support: []string{formula.StrategyPath},
+			wantErr: "D\t" + formula.StrategyPath,
+		},
+		{
+			name:    "formula renamed",
+			initial: map[string]string{"Formula/old.rb": testFormula("v1.0.0")},
+			write:   map[string]string{testFormulaPath: testFormula("v1.0.0")},
+			remove:  []string{"Formula/old.rb"},
+			wantErr: "Formula/old.rb",
+		},
+		{
+			name:    "formula unchanged",
+			initial: map[string]string{testFormulaPath: testFormula("v1.0.0")},
+			write:   map[string]string{"README.md": "tap"},
+			wantErr: "touches paths other than",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testTap(t, tt.initial)
+			commitFiles(t, dir, tt.write, tt.remove)
+
+			err := verifyTapCommit(dir, testFormulaPath, "v1.0.1", tt.support...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyTapCommitEmpty(t *testing.T) {
+	dir := testTap(t, map[string]string{testFormulaPath: testFormula("v1.0.0")})
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "empty")
+
+	err := verifyTapCommit(dir, testFormulaPath, "v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "does not change") {
+		t.Fatalf("error = %v, want it to report the formula unchanged", err)
+	}
+}
+
+func TestVerifyTapCommitDowngrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		previous   string // version of the formula in the tap, "" for none
+		newVersion string
+		wantErr    bool
+	}{
+		{"new formula", "", "v0.0.1", false},
+		{"patch upgrade", "v1.2.3", "v1.2.4", false},
+		{"major upgrade", "v1.9.9", "v2.0.0", false},
+		{"without v prefix", "1.2.3", "v1.2.4", false},
+		{"patch downgrade", "v1.2.3", "v1.2.2", true},
+		{"minor downgrade", "v1.10.0", "v1.9.0", true},
+		{"major downgrade", "v2.0.0", "v1.99.99", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initial := map[string]string{"README.md": "tap"}
+			if tt.previous != "" {
+				initial[testFormulaPath] = testFormula(tt.previous)
+			}
+			dir := testTap(t, initial)
+			commitFiles(t, dir, map[string]string{testFormulaPath: testFormula(tt.newVersion)}, nil)
+
+			err := verifyTapCommit(dir, testFormulaPath, tt.newVersion)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "downgraded") {
+					t.Fatalf("error = %v, want a downgrade error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
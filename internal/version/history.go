@@ -0,0 +1,43 @@
+package version
+
+import "time"
+
+// Release is one entry of the release history kept in the lock file
+type Release struct {
+	Version    string            `yaml:"version"` // release tag
+	Date       time.Time         `yaml:"date"`
+	Commit     string            `yaml:"commit,omitempty"`      // commit the tag points to
+	URL        string            `yaml:"url,omitempty"`         // source tarball URL
+	SHA256     string            `yaml:"sha256,omitempty"`      // source tarball checksum
+	Assets     map[string]string `yaml:"assets,omitempty"`      // asset name → SHA256
+	Taps       map[string]string `yaml:"taps,omitempty"`        // tap (owner/repo) → tap commit SHA
+	ReleasedBy string            `yaml:"released_by,omitempty"` // git user who ran the release
+}
+
+// AddRelease appends a release to the history. Entries are never rewritten.
+func (l *Lock) AddRelease(r Release) {
+	l.Releases = append(l.Releases, r)
+}
+
+// FindRelease returns the latest history entry for a tag or version
+// (e.g. v1.2.3 or 1.2.3), or nil if it was never recorded
+func (l *Lock) FindRelease(query string, format *TagFormat) *Release {
+	want := format.Version(query)
+	for i := len(l.Releases) - 1; i >= 0; i-- {
+		r := &l.Releases[i]
+		if r.Version == query || format.Version(r.Version) == want {
+			return r
+		}
+	}
+
+	// Locks written before the history existed only know the last release
+	if l.Version != "" && l.SHA256 != "" && format.Version(l.Version) == want {
+		return &Release{
+			Version: l.Version,
+			Date:    l.LastRelease,
+			SHA256:  l.SHA256,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultTagFormat is used when no version.tag_format is configured
+const DefaultTagFormat = "v{{.Version}}"
+
+var (
+	versionPlaceholderRe = regexp.MustCompile(`\{\{\s*\.Version\s*\}\}`)
+	semverRe             = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+)
+
+// TagFormat renders versions into release tags and parses them back,
+// e.g. "mytool-v{{.Version}}" maps 1.2.3 to mytool-v1.2.3
+type TagFormat struct {
+	prefix string
+	suffix string
+}
+
+// ParseTagFormat parses a tag format template. The template must contain
+// {{.Version}} exactly once; everything around it is literal text.
+func ParseTagFormat(format string) (*TagFormat, error) {
+	if format == "" {
+		format = DefaultTagFormat
+	}
+
+	locs := versionPlaceholderRe.FindAllStringIndex(format, -1)
+	if len(locs) != 1 {
+		return nil, fmt.Errorf("invalid tag format %q: must contain {{.Version}} exactly once", format)
+	}
+
+	f := &TagFormat{
+		prefix: format[:locs[0][0]],
+		suffix: format[locs[0][1]:],
+	}
+	if strings.Contains(f.prefix+f.suffix, "{{") {
+		return nil, fmt.Errorf("invalid tag format %q: only {{.Version}} is supported", format)
+	}
+	if strings.ContainsAny(f.prefix+f.suffix, " ~^:?*[\\") {
+		return nil, fmt.Errorf("invalid tag format %q: contains characters not allowed in git tags", format)
+	}
+	return f, nil
+}
+
+// String returns the format as a template
+func (f *TagFormat) String() string {
+	return f.prefix + "{{.Version}}" + f.suffix
+}
+
+// Format returns the tag of a version (1.2.3 or v1.2.3)
+func (f *TagFormat) Format(version string) string {
+	return f.prefix + strings.TrimPrefix(version, "v") + f.suffix
+}
+
+// Parse returns the version (1.2.3) of a tag, or false if the tag doesn't match the format
+func (f *TagFormat) Parse(tag string) (string, bool) {
+	if !strings.HasPrefix(tag, f.prefix) || !strings.HasSuffix(tag, f.suffix) || len(tag) < len(f.prefix)+len(f.suffix) {
+		return "", false
+	}
+	v := tag[len(f.prefix) : len(tag)-len(f.suffix)]
+	if !semverRe.MatchString(v) {
+		return "", false
+	}
+	return v, true
+}
+
+// Version returns the version of a tag like Parse, falling back to the
+// plain v1.2.3 form lock files used before tag formats existed
+func (f *TagFormat) Version(tag string) string {
+	if v, ok := f.Parse(tag); ok {
+		return v
+	}
+	return strings.TrimPrefix(tag, "v")
+}
+
+// Pattern returns a git tag -l pattern matching the tags of this format
+func (f *TagFormat) Pattern() string {
+	return f.prefix + "*" + f.suffix
+}
@@ -3,7 +3,6 @@ package version
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -15,10 +14,15 @@ const lockFile = "tobrew.lock"
 
 // Lock represents the tobrew.lock file
 type Lock struct {
-	Version     string    `yaml:"version"`
-	LastRelease time.Time `yaml:"last_release"`
+	Version     string    `yaml:"version,omitempty"` // tag of the last release, e.g. v1.2.3
+	LastRelease time.Time `yaml:"last_release,omitempty"`
 	SHA256      string    `yaml:"sha256,omitempty"`
-	Fingerprint string    `yaml:"fingerprint,omitempty"`
+
+	// Releases is the append-only history of releases
+	Releases []Release `yaml:"releases,omitempty"`
+
+	// Projects holds one entry per project of a multi-project repository
+	Projects map[string]*Lock `yaml:"projects,omitempty"`
 }
 
 // Load reads the lock file
@@ -26,10 +30,8 @@ func LoadLock() (*Lock, error) {
 	data, err := os.ReadFile(lockFile)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// No lock file yet - start with v0.0.0
-			return &Lock{
-				Version: "v0.0.0",
-			}, nil
+			// No lock file yet - entries start with v0.0.0
+			return &Lock{}, nil
 		}
 		return nil, err
 	}
@@ -42,6 +44,27 @@ func LoadLock() (*Lock, error) {
 	return &lock, nil
 }
 
+// Entry returns the lock entry of a project, or the lock itself for
+// single-project repositories (empty name). New entries start at 0.0.0,
+// rendered with format.
+func (l *Lock) Entry(project string, format *TagFormat) *Lock {
+	entry := l
+	if project != "" {
+		if l.Projects == nil {
+			l.Projects = map[string]*Lock{}
+		}
+		if l.Projects[project] == nil {
+			l.Projects[project] = &Lock{}
+		}
+		entry = l.Projects[project]
+	}
+
+	if entry.Version == "" {
+		entry.Version = format.Format("0.0.0")
+	}
+	return entry
+}
+
 // Save writes the lock file
 func (l *Lock) Save() error {
 	data, err := yaml.Marshal(l)
@@ -61,17 +84,28 @@ const (
 	BumpMajor                 // +1.0.0
 )
 
-// Bump increments the version according to bump type
-func (l *Lock) Bump(bumpType BumpType) (string, error) {
-	current := l.Version
-	if !strings.HasPrefix(current, "v") {
-		return "", fmt.Errorf("invalid version format: %s (must start with 'v')", current)
+// Bump increments the version according to bump type and stores the tag
+// of the new version, rendered with format. It returns the new tag.
+func (l *Lock) Bump(bumpType BumpType, format *TagFormat) (string, error) {
+	newTag, err := Next(l.Version, bumpType, format)
+	if err != nil {
+		return "", err
 	}
 
-	// Parse version: v1.2.3 -> [1, 2, 3]
-	parts := strings.Split(current[1:], ".")
+	l.Version = newTag
+	l.LastRelease = time.Now()
+
+	return newTag, nil
+}
+
+// Next returns the tag following currentTag for a bump type
+func Next(currentTag string, bumpType BumpType, format *TagFormat) (string, error) {
+	current := format.Version(currentTag)
+
+	// Parse version: 1.2.3 -> [1, 2, 3]
+	parts := strings.Split(current, ".")
 	if len(parts) != 3 {
-		return "", fmt.Errorf("invalid version format: %s (expected v1.2.3)", current)
+		return "", fmt.Errorf("invalid version format: %s (expected %s)", currentTag, format.Format("1.2.3"))
 	}
 
 	major, err := strconv.Atoi(parts[0])
@@ -102,48 +136,49 @@ func (l *Lock) Bump(bumpType BumpType) (string, error) {
 		patch++
 	}
 
-	newVersion := fmt.Sprintf("v%d.%d.%d", major, minor, patch)
-	l.Version = newVersion
-	l.LastRelease = time.Now()
-
-	return newVersion, nil
+	return format.Format(fmt.Sprintf("%d.%d.%d", major, minor, patch)), nil
 }
 
-// UpdateSHA256 updates the SHA256 in lock file
-func (l *Lock) UpdateSHA256(sha256 string) {
-	l.SHA256 = sha256
-}
-
-// GetFingerprint returns a unique identifier for current machine
-func GetFingerprint() string {
-	// Try Linux machine-id first
-	if data, err := os.ReadFile("/etc/machine-id"); err == nil {
-		return strings.TrimSpace(string(data))
-	}
-
-	// Try macOS Hardware UUID
-	if data, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output(); err == nil {
-		for _, line := range strings.Split(string(data), "\n") {
-			if strings.Contains(line, "IOPlatformUUID") {
-				parts := strings.Split(line, "=")
-				if len(parts) == 2 {
-					return strings.Trim(strings.TrimSpace(parts[1]), "\"")
-				}
-			}
+// Compare compares two semver strings (v1.2.3 format)
+// Returns: 1 if a > b, -1 if a < b, 0 if equal
+func Compare(a, b string) int {
+	parseVersion := func(v string) (int, int, int) {
+		v = strings.TrimPrefix(v, "v")
+		parts := strings.Split(v, ".")
+		if len(parts) != 3 {
+			return 0, 0, 0
 		}
+		major, _ := strconv.Atoi(parts[0])
+		minor, _ := strconv.Atoi(parts[1])
+		patch, _ := strconv.Atoi(parts[2])
+		return major, minor, patch
 	}
 
-	// Fallback to hostname
-	hostname, _ := os.Hostname()
-	return hostname
-}
+	aMajor, aMinor, aPatch := parseVersion(a)
+	bMajor, bMinor, bPatch := parseVersion(b)
 
-// IsSameMachine checks if lock was created on current machine
-func (l *Lock) IsSameMachine() bool {
-	return l.Fingerprint == "" || l.Fingerprint == GetFingerprint()
+	if aMajor != bMajor {
+		if aMajor > bMajor {
+			return 1
+		}
+		return -1
+	}
+	if aMinor != bMinor {
+		if aMinor > bMinor {
+			return 1
+		}
+		return -1
+	}
+	if aPatch != bPatch {
+		if aPatch > bPatch {
+			return 1
+		}
+		return -1
+	}
+	return 0
 }
 
-// UpdateFingerprint sets current machine fingerprint
-func (l *Lock) UpdateFingerprint() {
-	l.Fingerprint = GetFingerprint()
+// UpdateSHA256 updates the SHA256 in lock file
+func (l *Lock) UpdateSHA256(sha256 string) {
+	l.SHA256 = sha256
 }
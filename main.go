@@ -32,8 +32,14 @@ Simple workflow:
 	}
 
 	rootCmd.AddCommand(cmd.InitCmd())
+	rootCmd.AddCommand(cmd.ConfigCmd())
 	rootCmd.AddCommand(cmd.ReleaseCmd())
 	rootCmd.AddCommand(cmd.SyncCmd())
+	rootCmd.AddCommand(cmd.VersionCmd())
+	rootCmd.AddCommand(cmd.StatusCmd())
+	rootCmd.AddCommand(cmd.HistoryCmd())
+	rootCmd.AddCommand(cmd.VerifyCmd())
+	rootCmd.AddCommand(cmd.DoctorCmd())
 	rootCmd.AddCommand(cmd.InstallCmd())
 	rootCmd.AddCommand(cmd.SelfUpdateCmd())
 